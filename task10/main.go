@@ -1,70 +1,203 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"strings"
+	"path/filepath"
 	"task10/sort"
-	"unicode"
+)
+
+const (
+	exitDisorder = 1
+	exitError    = 2
 )
 
 func main() {
+	var keys sort.KeyList
+	flag.Var(&keys, "k", "sort key POS1[,POS2][bdfghMnRrV], may be repeated (e.g. 2,2n)")
+	numeric := flag.Bool("n", false, "numeric sort")
+	general := flag.Bool("g", false, "general numeric sort (scientific notation, inf, nan)")
+	reverse := flag.Bool("r", false, "reverse order")
+	unique := flag.Bool("u", false, "output only the first of lines with equal keys")
+	count := flag.Bool("count", false, "prefix each output line with the number of equal-key lines it replaces (implies -u)")
+	monthSort := flag.Bool("M", false, "month sort")
+	ignoreBlanks := flag.Bool("b", false, "ignore trailing blanks")
+	checkOnly := flag.Bool("c", false, "check if sorted")
+	merge := flag.Bool("m", false, "merge already sorted files; do not sort")
+	humanSort := flag.Bool("h", false, "human-readable sizes (10K, 2M, 1.5GiB, 3kB)")
+	randomSort := flag.Bool("R", false, "shuffle, but group identical keys")
+	randomSource := flag.String("random-source", "", "get random bytes from file")
+	seed := flag.Int64("seed", 0, "seed for -R to make the shuffle reproducible (0 picks a random one)")
+	versionSort := flag.Bool("V", false, "natural sort of (version) numbers within text")
+	foldCase := flag.Bool("f", false, "fold lower case to upper case characters")
+	dictionary := flag.Bool("d", false, "consider only blanks and alphanumeric characters")
+	locale := flag.String("locale", "", "collate text by Unicode rules for a locale tag (e.g. ru, de-DE)")
+	separator := flag.String("t", "", "field separator (default: runs of blanks)")
+	csv := flag.Bool("csv", false, "parse fields as RFC 4180 CSV (separator defaults to ',')")
+	debug := flag.Bool("debug", false, "annotate the part of each line used as a sort key")
+	stable := flag.Bool("s", false, "stable sort: keep input order of lines with equal keys")
+	parallel := flag.Int("parallel", 1, "number of sorts run concurrently")
+	bufferSize := flag.String("S", "", "main memory buffer size (e.g. 512K, 100M, 1G)")
+	tempDir := flag.String("T", "", "directory for temporary files")
+	output := flag.String("o", "", "write result to file instead of standard output")
+	zeroTerminated := flag.Bool("z", false, "line delimiter is NUL, not newline")
+
 	flag.Parse()
 
-	var lines []string
-	args := flag.Args()
+	opts := sort.Options{
+		Keys:           keys,
+		Numeric:        *numeric,
+		General:        *general,
+		Reverse:        *reverse,
+		Unique:         *unique,
+		Count:          *count,
+		Month:          *monthSort,
+		IgnoreBlanks:   *ignoreBlanks,
+		Human:          *humanSort,
+		Version:        *versionSort,
+		Random:         *randomSort,
+		FoldCase:       *foldCase,
+		Dictionary:     *dictionary,
+		Locale:         *locale,
+		Separator:      unescapeSeparator(*separator),
+		CSV:            *csv,
+		Stable:         *stable,
+		Debug:          *debug,
+		Parallel:       *parallel,
+		ZeroTerminated: *zeroTerminated,
+		TempDir:        *tempDir,
+	}
+	if *bufferSize != "" {
+		size, err := sort.ParseSize(*bufferSize)
+		if err != nil {
+			fatal(err)
+		}
+		opts.BufferSize = size
+	}
 
-	if len(args) > 0 {
-		for _, fileName := range args {
-			if err := readFileLines(fileName, &lines); err != nil {
-				log.Fatal(err)
-			}
+	switch {
+	case *randomSource != "":
+		salt, err := readSalt(*randomSource)
+		if err != nil {
+			fatal(err)
 		}
-	} else {
-		scanner := bufio.NewScanner(os.Stdin)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if *sort.IgnoreBlanks {
-				line = strings.TrimRightFunc(line, unicode.IsSpace)
+		opts.RandomSalt = salt
+	case *seed != 0:
+		opts.RandomSalt = sort.SeedSalt(*seed)
+	}
+
+	sorter, err := sort.New(opts)
+	if err != nil {
+		fatal(err)
+	}
+
+	var inputs []io.Reader
+	for _, fileName := range flag.Args() {
+		f, err := os.Open(fileName)
+		if err != nil {
+			fatal(err)
+		}
+		defer func(f *os.File) {
+			err := f.Close()
+			if err != nil {
+				fatal(err)
 			}
-			lines = append(lines, line)
+		}(f)
+		inputs = append(inputs, f)
+	}
+	if len(inputs) == 0 {
+		inputs = append(inputs, os.Stdin)
+	}
+
+	delim := byte('\n')
+	if *zeroTerminated {
+		delim = 0
+	}
+	input := sort.Concat(delim, inputs...)
+
+	if *checkOnly {
+		ok, line, err := sorter.Check(input)
+		if err != nil {
+			fatal(err)
+		}
+		if !ok {
+			fmt.Fprintf(os.Stderr, "sort: disorder at line %d\n", line)
+			os.Exit(exitDisorder)
 		}
-		if err := scanner.Err(); err != nil {
-			log.Fatal(err)
+		return
+	}
+
+	run := func(w io.Writer) error {
+		if *merge {
+			return sorter.Merge(inputs, w)
 		}
+		return sorter.Sort(input, w)
 	}
 
-	result := sort.ProcessLines(lines)
-	for _, line := range result {
-		fmt.Println(line)
+	if *output == "" {
+		if err := run(os.Stdout); err != nil {
+			fatal(err)
+		}
+		return
 	}
+	if err := writeFile(*output, run); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	log.Print(err)
+	os.Exit(exitError)
 }
 
-func readFileLines(fileName string, lines *[]string) error {
+func readSalt(fileName string) ([]byte, error) {
 	f, err := os.Open(fileName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func(f *os.File) {
-		err := f.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
+		_ = f.Close()
 	}(f)
+	return sort.ReadSalt(f)
+}
 
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if *sort.IgnoreBlanks {
-			line = strings.TrimRightFunc(line, unicode.IsSpace)
-		}
-		*lines = append(*lines, line)
+func writeFile(fileName string, write func(io.Writer) error) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(fileName); err == nil {
+		mode = info.Mode().Perm()
 	}
-	if err := scanner.Err(); err != nil {
+
+	tmp, err := os.CreateTemp(filepath.Dir(fileName), ".sort-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if err := write(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
 		return err
 	}
-	return nil
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fileName)
+}
+
+func unescapeSeparator(sep string) string {
+	switch sep {
+	case `\t`:
+		return "\t"
+	case `\0`:
+		return "\x00"
+	}
+	return sep
 }
@@ -0,0 +1,58 @@
+package sort
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+func (s *Sorter) annotate(line string) []string {
+	var notes []string
+	for _, key := range s.keys {
+		start, end := 0, len(line)
+		if len(s.opts.Keys) > 0 {
+			start, end, _ = s.keySpan(line, key)
+		}
+
+		off, n, ok := parsedSpan(line[start:end], key)
+		notes = append(notes, debugMarker(line, start+off, start+off+n, ok))
+	}
+	if !s.stable() && (len(s.opts.Keys) > 0 || s.keys[0].hasOptions()) {
+		notes = append(notes, debugMarker(line, 0, len(line), true))
+	}
+	return notes
+}
+
+func parsedSpan(key string, spec KeySpec) (int, int, bool) {
+	var off, n int
+	var ok bool
+	switch {
+	case spec.Numeric:
+		_, off, n, ok = parseNumeric(key)
+	case spec.General:
+		_, off, n, ok = parseGeneral(key)
+	case spec.Month:
+		_, off, n, ok = monthOf(key)
+	case spec.Human:
+		_, off, n, ok = parseHuman(key)
+	default:
+		return 0, len(key), true
+	}
+	return off, n, ok
+}
+
+func debugMarker(line string, start, end int, ok bool) string {
+	var b strings.Builder
+	for _, r := range line[:start] {
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	if !ok || start == end {
+		b.WriteString("^ no match for key")
+		return b.String()
+	}
+	b.WriteString(strings.Repeat("_", utf8.RuneCountInString(line[start:end])))
+	return b.String()
+}
@@ -0,0 +1,461 @@
+package sort
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultBufferSize = 64 << 20
+	lineOverhead      = 16
+	mergeFanIn        = 64
+)
+
+type lineReader interface {
+	next() (string, bool, error)
+}
+
+type recordReader struct {
+	r     *bufio.Reader
+	delim byte
+}
+
+func newRecordReader(r io.Reader, delim byte) *recordReader {
+	return &recordReader{r: bufio.NewReader(r), delim: delim}
+}
+
+func (r *recordReader) next() (string, bool, error) {
+	record, err := r.r.ReadString(r.delim)
+	if err == io.EOF {
+		return record, record != "", nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return record[:len(record)-1], true, nil
+}
+
+type sliceReader struct {
+	lines []string
+}
+
+func (r *sliceReader) next() (string, bool, error) {
+	if len(r.lines) == 0 {
+		return "", false, nil
+	}
+	line := r.lines[0]
+	r.lines = r.lines[1:]
+	return line, true, nil
+}
+
+type concatReader struct {
+	readers []io.Reader
+	delim   byte
+	last    byte
+	pending bool
+}
+
+func Concat(delim byte, readers ...io.Reader) io.Reader {
+	return &concatReader{readers: readers, delim: delim, last: delim}
+}
+
+func (c *concatReader) Read(p []byte) (int, error) {
+	for len(c.readers) > 0 || c.pending {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		if c.pending {
+			c.pending = false
+			c.last = c.delim
+			p[0] = c.delim
+			return 1, nil
+		}
+
+		n, err := c.readers[0].Read(p)
+		if n > 0 {
+			c.last = p[n-1]
+		}
+		if err == io.EOF {
+			c.readers = c.readers[1:]
+			c.pending = c.last != c.delim
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+	return 0, io.EOF
+}
+
+func (s *Sorter) Sort(r io.Reader, w io.Writer) error {
+	out := bufio.NewWriter(w)
+	emit := s.newEmitter(s.writeRecord(out))
+
+	var runs []string
+	defer func() {
+		for _, run := range runs {
+			_ = os.Remove(run)
+		}
+	}()
+
+	var chunk []string
+	size := int64(0)
+	records := newRecordReader(r, s.delim())
+	for {
+		line, ok, err := records.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		line = s.trimRecord(line)
+		chunk = append(chunk, line)
+		size += int64(len(line)) + lineOverhead
+		if size >= s.opts.BufferSize {
+			run, err := s.spillRun(s.sortLines(chunk))
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+			chunk = nil
+			size = 0
+		}
+	}
+
+	sources := []lineReader{&sliceReader{lines: s.sortLines(chunk)}}
+	if len(runs) > 0 {
+		for len(runs) >= mergeFanIn {
+			merged, err := s.mergeToRun(runs[:mergeFanIn])
+			if err != nil {
+				return err
+			}
+			runs = append([]string{merged}, runs[mergeFanIn:]...)
+		}
+
+		files, err := openRuns(runs)
+		if err != nil {
+			return err
+		}
+		defer closeRuns(files)
+		sources = append(s.runSources(files), sources...)
+	}
+
+	if err := s.mergeLines(sources, emit.emit); err != nil {
+		return err
+	}
+	if err := emit.flush(); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+func (s *Sorter) Merge(inputs []io.Reader, w io.Writer) error {
+	out := bufio.NewWriter(w)
+
+	sources := make([]lineReader, 0, len(inputs))
+	for i, r := range inputs {
+		sources = append(sources, &sortedReader{
+			s:       s,
+			records: newRecordReader(r, s.delim()),
+			input:   i + 1,
+		})
+	}
+
+	emit := s.newEmitter(s.writeRecord(out))
+	if err := s.mergeLines(sources, emit.emit); err != nil {
+		return err
+	}
+	if err := emit.flush(); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+type sortedReader struct {
+	s       *Sorter
+	records *recordReader
+	input   int
+	line    int
+	prev    string
+}
+
+func (r *sortedReader) next() (string, bool, error) {
+	line, ok, err := r.records.next()
+	if !ok || err != nil {
+		return "", false, err
+	}
+	line = r.s.trimRecord(line)
+	r.line++
+	if r.line > 1 && r.s.compare(line, r.prev) < 0 {
+		return "", false, fmt.Errorf("input %d is not sorted: disorder at line %d", r.input, r.line)
+	}
+	r.prev = line
+	return line, true, nil
+}
+
+func (s *Sorter) delim() byte {
+	if s.opts.ZeroTerminated {
+		return 0
+	}
+	return '\n'
+}
+
+func (s *Sorter) trimRecord(line string) string {
+	if s.opts.IgnoreBlanks {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+	return line
+}
+
+type emitter struct {
+	s       *Sorter
+	write   func(line string, count int) error
+	pending string
+	count   int
+}
+
+func (s *Sorter) newEmitter(write func(line string, count int) error) *emitter {
+	return &emitter{s: s, write: write}
+}
+
+func (e *emitter) emit(line string) error {
+	if !e.s.unique() {
+		return e.write(line, 0)
+	}
+	if e.count > 0 && e.s.compareByKeys(line, e.pending) == 0 {
+		e.count++
+		return nil
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	e.pending, e.count = line, 1
+	return nil
+}
+
+func (e *emitter) flush() error {
+	if e.count == 0 {
+		return nil
+	}
+	line, count := e.pending, e.count
+	e.pending, e.count = "", 0
+	return e.write(line, count)
+}
+
+func (s *Sorter) formatRecord(line string, count int) string {
+	if !s.opts.Count {
+		return line
+	}
+	return fmt.Sprintf("%7d ", count) + line
+}
+
+func (s *Sorter) writeRecord(w *bufio.Writer) func(string, int) error {
+	return func(line string, count int) error {
+		record := s.formatRecord(line, count)
+		if _, err := w.WriteString(record); err != nil {
+			return err
+		}
+		if err := w.WriteByte(s.delim()); err != nil {
+			return err
+		}
+		if s.opts.Debug {
+			indent := strings.Repeat(" ", len(record)-len(line))
+			for _, note := range s.annotate(line) {
+				if _, err := w.WriteString(indent + note + "\n"); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func (s *Sorter) spillRun(lines []string) (string, error) {
+	f, err := os.CreateTemp(s.opts.TempDir, "sort-run-*")
+	if err != nil {
+		return "", err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, line := range lines {
+		if _, err = w.WriteString(line); err != nil {
+			break
+		}
+		if err = w.WriteByte(s.delim()); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func (s *Sorter) mergeToRun(runs []string) (string, error) {
+	files, err := openRuns(runs)
+	if err != nil {
+		return "", err
+	}
+	defer closeRuns(files)
+
+	f, err := os.CreateTemp(s.opts.TempDir, "sort-run-*")
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriter(f)
+	err = s.mergeLines(s.runSources(files), func(line string) error {
+		if _, err := w.WriteString(line); err != nil {
+			return err
+		}
+		return w.WriteByte(s.delim())
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+
+	for _, run := range runs {
+		_ = os.Remove(run)
+	}
+	return f.Name(), nil
+}
+
+func openRuns(runs []string) ([]*os.File, error) {
+	files := make([]*os.File, 0, len(runs))
+	for _, run := range runs {
+		f, err := os.Open(run)
+		if err != nil {
+			closeRuns(files)
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func closeRuns(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
+
+func (s *Sorter) runSources(files []*os.File) []lineReader {
+	sources := make([]lineReader, 0, len(files))
+	for _, f := range files {
+		sources = append(sources, newRecordReader(f, s.delim()))
+	}
+	return sources
+}
+
+type mergeItem struct {
+	line string
+	src  int
+}
+
+type mergeHeap struct {
+	items []mergeItem
+	s     *Sorter
+}
+
+func (h *mergeHeap) Len() int { return len(h.items) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	if res := h.s.compare(h.items[i].line, h.items[j].line); res != 0 {
+		return res < 0
+	}
+	return h.items[i].src < h.items[j].src
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *mergeHeap) Push(x interface{}) { h.items = append(h.items, x.(mergeItem)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := h.items
+	item := old[len(old)-1]
+	h.items = old[:len(old)-1]
+	return item
+}
+
+func (s *Sorter) mergeLines(sources []lineReader, emit func(string) error) error {
+	h := &mergeHeap{items: make([]mergeItem, 0, len(sources)), s: s}
+	for i, src := range sources {
+		line, ok, err := src.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.items = append(h.items, mergeItem{line: line, src: i})
+		}
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		item := h.items[0]
+		if err := emit(item.line); err != nil {
+			return err
+		}
+		line, ok, err := sources[item.src].next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.items[0].line = line
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return nil
+}
+
+func ParseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("invalid buffer size: %q", s)
+	}
+
+	mult := int64(1024)
+	num := s
+	switch s[len(s)-1] {
+	case 'b':
+		mult = 1
+		num = s[:len(s)-1]
+	case 'K', 'k':
+		num = s[:len(s)-1]
+	case 'M', 'm':
+		mult = 1 << 20
+		num = s[:len(s)-1]
+	case 'G', 'g':
+		mult = 1 << 30
+		num = s[:len(s)-1]
+	case 'T', 't':
+		mult = 1 << 40
+		num = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid buffer size: %s", s)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,352 @@
+package sort
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type KeySpec struct {
+	StartField int
+	StartChar  int
+	EndField   int
+	EndChar    int
+
+	Numeric     bool
+	General     bool
+	Month       bool
+	Human       bool
+	Reverse     bool
+	Version     bool
+	Random      bool
+	FoldCase    bool
+	Dictionary  bool
+	StartBlanks bool
+	EndBlanks   bool
+}
+
+type KeyList []KeySpec
+
+func (k *KeyList) String() string {
+	if k == nil {
+		return ""
+	}
+	specs := make([]string, 0, len(*k))
+	for _, spec := range *k {
+		specs = append(specs, spec.String())
+	}
+	return strings.Join(specs, " ")
+}
+
+func (k *KeyList) Set(value string) error {
+	spec, err := ParseKey(value)
+	if err != nil {
+		return err
+	}
+	*k = append(*k, spec)
+	return nil
+}
+
+func (s KeySpec) String() string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(s.StartField))
+	if s.StartChar > 1 {
+		b.WriteString("." + strconv.Itoa(s.StartChar))
+	}
+	if s.EndField > 0 {
+		b.WriteString("," + strconv.Itoa(s.EndField))
+		if s.EndChar > 0 {
+			b.WriteString("." + strconv.Itoa(s.EndChar))
+		}
+	}
+	b.WriteString(s.modifiers())
+	return b.String()
+}
+
+func (s KeySpec) modifiers() string {
+	var b strings.Builder
+	if s.StartBlanks || s.EndBlanks {
+		b.WriteByte('b')
+	}
+	if s.Dictionary {
+		b.WriteByte('d')
+	}
+	if s.FoldCase {
+		b.WriteByte('f')
+	}
+	if s.General {
+		b.WriteByte('g')
+	}
+	if s.Human {
+		b.WriteByte('h')
+	}
+	if s.Month {
+		b.WriteByte('M')
+	}
+	if s.Numeric {
+		b.WriteByte('n')
+	}
+	if s.Random {
+		b.WriteByte('R')
+	}
+	if s.Reverse {
+		b.WriteByte('r')
+	}
+	if s.Version {
+		b.WriteByte('V')
+	}
+	return b.String()
+}
+
+func ParseKey(spec string) (KeySpec, error) {
+	var key KeySpec
+
+	startPart, endPart := spec, ""
+	if i := strings.IndexByte(spec, ','); i >= 0 {
+		startPart, endPart = spec[:i], spec[i+1:]
+	}
+
+	field, char, mods, err := parseKeyPos(startPart)
+	if err != nil || field <= 0 || char < 0 {
+		return key, fmt.Errorf("invalid key: %s", spec)
+	}
+	if char == 0 {
+		char = 1
+	}
+	key.StartField, key.StartChar = field, char
+	if err := key.applyModifiers(mods, false); err != nil {
+		return key, fmt.Errorf("invalid key: %s: %v", spec, err)
+	}
+
+	if endPart != "" {
+		field, char, mods, err := parseKeyPos(endPart)
+		if err != nil || field <= 0 || char < 0 {
+			return key, fmt.Errorf("invalid key: %s", spec)
+		}
+		key.EndField, key.EndChar = field, char
+		if err := key.applyModifiers(mods, true); err != nil {
+			return key, fmt.Errorf("invalid key: %s: %v", spec, err)
+		}
+	}
+
+	return key, nil
+}
+
+func parseKeyPos(pos string) (int, int, string, error) {
+	i := 0
+	for i < len(pos) && pos[i] >= '0' && pos[i] <= '9' {
+		i++
+	}
+	field, err := strconv.Atoi(pos[:i])
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	char := 0
+	if i < len(pos) && pos[i] == '.' {
+		j := i + 1
+		for j < len(pos) && pos[j] >= '0' && pos[j] <= '9' {
+			j++
+		}
+		char, err = strconv.Atoi(pos[i+1 : j])
+		if err != nil {
+			return 0, 0, "", err
+		}
+		i = j
+	}
+
+	return field, char, pos[i:], nil
+}
+
+func (s *KeySpec) applyModifiers(mods string, end bool) error {
+	for _, m := range mods {
+		switch m {
+		case 'b':
+			if end {
+				s.EndBlanks = true
+			} else {
+				s.StartBlanks = true
+			}
+		case 'n':
+			s.Numeric = true
+		case 'g':
+			s.General = true
+		case 'M':
+			s.Month = true
+		case 'h':
+			s.Human = true
+		case 'r':
+			s.Reverse = true
+		case 'V':
+			s.Version = true
+		case 'R':
+			s.Random = true
+		case 'f':
+			s.FoldCase = true
+		case 'd':
+			s.Dictionary = true
+		default:
+			return fmt.Errorf("unknown modifier %q", m)
+		}
+	}
+	return nil
+}
+
+func (s KeySpec) hasOptions() bool {
+	return s.modifiers() != ""
+}
+
+type field struct {
+	start, end int
+	quoted     bool
+}
+
+func (s *Sorter) getKey(line string, spec KeySpec) string {
+	start, end, quoted := s.keySpan(line, spec)
+	key := line[start:end]
+	if quoted {
+		key = strings.ReplaceAll(key, `""`, `"`)
+	}
+	return key
+}
+
+func (s *Sorter) keySpan(line string, spec KeySpec) (int, int, bool) {
+	fields := s.splitFields(line)
+
+	if spec.StartField > len(fields) {
+		return len(line), len(line), false
+	}
+	first := fields[spec.StartField-1]
+	fieldStart := first.start
+	if spec.StartBlanks {
+		fieldStart = skipBlanks(line, fieldStart, first.end)
+	}
+	start := advanceRunes(line, fieldStart, first.end, spec.StartChar-1)
+
+	end := len(line)
+	last := fields[len(fields)-1]
+	if spec.EndField > 0 && spec.EndField <= len(fields) {
+		last = fields[spec.EndField-1]
+		if spec.EndChar == 0 {
+			end = last.end
+		} else {
+			fieldStart = last.start
+			if spec.EndBlanks {
+				fieldStart = skipBlanks(line, fieldStart, last.end)
+			}
+			end = advanceRunes(line, fieldStart, last.end, spec.EndChar)
+		}
+	}
+
+	if end <= start {
+		return start, start, false
+	}
+	return start, end, first == last && first.quoted
+}
+
+func (s *Sorter) splitFields(line string) []field {
+	switch {
+	case s.opts.CSV:
+		sep := s.opts.Separator
+		if sep == "" {
+			sep = ","
+		}
+		return splitCSV(line, sep)
+	case s.opts.Separator != "":
+		return splitSeparated(line, s.opts.Separator)
+	default:
+		return splitBlankRuns(line)
+	}
+}
+
+func splitSeparated(line, sep string) []field {
+	var fields []field
+	start := 0
+	for {
+		i := strings.Index(line[start:], sep)
+		if i < 0 {
+			break
+		}
+		fields = append(fields, field{start: start, end: start + i})
+		start += i + len(sep)
+	}
+	return append(fields, field{start: start, end: len(line)})
+}
+
+func splitBlankRuns(line string) []field {
+	var fields []field
+	start := 0
+	for i := 1; i < len(line); i++ {
+		if isBlank(line[i]) && !isBlank(line[i-1]) {
+			fields = append(fields, field{start: start, end: i})
+			start = i
+		}
+	}
+	return append(fields, field{start: start, end: len(line)})
+}
+
+func splitCSV(line, sep string) []field {
+	var fields []field
+	pos := 0
+	for {
+		f := field{start: pos, end: len(line)}
+		if pos < len(line) && line[pos] == '"' {
+			f.quoted = true
+			f.start = pos + 1
+			i := pos + 1
+			for i < len(line) {
+				if line[i] == '"' {
+					if i+1 < len(line) && line[i+1] == '"' {
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+			f.end = i
+			pos = i + 1
+		}
+
+		next := -1
+		if pos <= len(line) {
+			next = strings.Index(line[pos:], sep)
+		}
+		if next < 0 {
+			if !f.quoted {
+				f.end = len(line)
+			}
+			return append(fields, f)
+		}
+		if !f.quoted {
+			f.end = pos + next
+		}
+		fields = append(fields, f)
+		pos += next + len(sep)
+	}
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func skipBlanks(line string, pos, limit int) int {
+	for pos < limit {
+		r, size := utf8.DecodeRuneInString(line[pos:limit])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
+
+func advanceRunes(line string, pos, limit, n int) int {
+	for ; n > 0 && pos < limit; n-- {
+		_, size := utf8.DecodeRuneInString(line[pos:limit])
+		pos += size
+	}
+	return pos
+}
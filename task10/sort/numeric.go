@@ -0,0 +1,181 @@
+package sort
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var humanSuffixes = map[byte]int{
+	'K': 1, 'k': 1, 'M': 2, 'm': 2, 'G': 3, 'g': 3,
+	'T': 4, 't': 4, 'P': 5, 'p': 5, 'E': 6, 'e': 6,
+}
+
+func trimBlanks(s string) (string, int) {
+	trimmed := strings.TrimLeft(s, " \t")
+	return trimmed, len(s) - len(trimmed)
+}
+
+func numericPrefix(s string) int {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	digits := 0
+	for i < len(s) {
+		switch {
+		case isDigit(s[i]):
+			digits++
+			i++
+			continue
+		case s[i] == ',' && digits > 0 && isThousandsGroup(s[i+1:]):
+			i++
+			continue
+		}
+		break
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && isDigit(s[i]) {
+			digits++
+			i++
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+	return i
+}
+
+func isThousandsGroup(s string) bool {
+	if len(s) < 3 || !isDigit(s[0]) || !isDigit(s[1]) || !isDigit(s[2]) {
+		return false
+	}
+	return len(s) == 3 || !isDigit(s[3])
+}
+
+func parseNumeric(s string) (float64, int, int, bool) {
+	s, off := trimBlanks(s)
+	n := numericPrefix(s)
+	if n == 0 {
+		return 0, off, 0, false
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s[:n], ",", ""), 64)
+	if err != nil {
+		return 0, off, 0, false
+	}
+	return v, off, n, true
+}
+
+func parseGeneral(s string) (float64, int, int, bool) {
+	s, off := trimBlanks(s)
+	for n := floatPrefix(s); n > 0; n-- {
+		if v, err := strconv.ParseFloat(s[:n], 64); err == nil || errors.Is(err, strconv.ErrRange) {
+			return v, off, n, true
+		}
+	}
+	return 0, off, 0, false
+}
+
+func floatPrefix(s string) int {
+	i := 0
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		i++
+	}
+	for _, word := range []string{"infinity", "inf", "nan"} {
+		if len(s)-i >= len(word) && strings.EqualFold(s[i:i+len(word)], word) {
+			return i + len(word)
+		}
+	}
+	for i < len(s) && (isDigit(s[i]) || s[i] == '.') {
+		i++
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '-' || s[j] == '+') {
+			j++
+		}
+		if j < len(s) && isDigit(s[j]) {
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			i = j
+		}
+	}
+	return i
+}
+
+func parseHuman(s string) (float64, int, int, bool) {
+	v, off, n, ok := parseNumeric(s)
+	if !ok {
+		return 0, off, 0, false
+	}
+
+	rest := s[off+n:]
+	if rest == "" {
+		return v, off, n, true
+	}
+	power, ok := humanSuffixes[rest[0]]
+	if !ok {
+		return v, off, n, true
+	}
+
+	base := 1024.0
+	n++
+	switch {
+	case strings.HasPrefix(rest[1:], "iB"):
+		n += 2
+	case strings.HasPrefix(rest[1:], "B"):
+		base = 1000
+		n++
+	}
+	return v * math.Pow(base, float64(power)), off, n, true
+}
+
+func monthOf(s string) (int, int, int, bool) {
+	s, off := trimBlanks(s)
+	if len(s) < 3 {
+		return 0, off, 0, false
+	}
+	for name, m := range months {
+		if strings.EqualFold(s[:3], name) {
+			return m, off, 3, true
+		}
+	}
+	return 0, off, 0, false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareGeneral(ka, kb string) int {
+	a, _, _, okA := parseGeneral(ka)
+	b, _, _, okB := parseGeneral(kb)
+	if res := compareInt(generalRank(a, okA), generalRank(b, okB)); res != 0 {
+		return res
+	}
+	if !okA || math.IsNaN(a) {
+		return 0
+	}
+	return compareFloat(a, b)
+}
+
+func generalRank(v float64, ok bool) int {
+	switch {
+	case !ok:
+		return 0
+	case math.IsNaN(v):
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,73 @@
+package sort
+
+import "sync"
+
+const minParallelLines = 1024
+
+func (s *Sorter) parallelSort(lines []string, workers int) []string {
+	if workers > len(lines) {
+		workers = len(lines)
+	}
+
+	size := (len(lines) + workers - 1) / workers
+	parts := make([][]string, 0, workers)
+	for start := 0; start < len(lines); start += size {
+		end := start + size
+		if end > len(lines) {
+			end = len(lines)
+		}
+		parts = append(parts, lines[start:end])
+	}
+
+	var wg sync.WaitGroup
+	for _, part := range parts {
+		wg.Add(1)
+		go func(part []string) {
+			defer wg.Done()
+			s.sequentialSort(part)
+		}(part)
+	}
+	wg.Wait()
+
+	src, dst := lines, make([]string, len(lines))
+	for len(parts) > 1 {
+		merged := make([][]string, 0, (len(parts)+1)/2)
+		offset := 0
+		for i := 0; i < len(parts); i += 2 {
+			if i+1 == len(parts) {
+				out := dst[offset : offset+len(parts[i])]
+				copy(out, parts[i])
+				merged = append(merged, out)
+				break
+			}
+			out := dst[offset : offset+len(parts[i])+len(parts[i+1])]
+			wg.Add(1)
+			go func(a, b, out []string) {
+				defer wg.Done()
+				s.mergeTwo(a, b, out)
+			}(parts[i], parts[i+1], out)
+			merged = append(merged, out)
+			offset += len(out)
+		}
+		wg.Wait()
+		parts = merged
+		src, dst = dst, src
+	}
+	return src
+}
+
+func (s *Sorter) mergeTwo(a, b, out []string) {
+	i, j, k := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if s.compare(b[j], a[i]) < 0 {
+			out[k] = b[j]
+			j++
+		} else {
+			out[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(out[k:], a[i:])
+	copy(out[k:], b[j:])
+}
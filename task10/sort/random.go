@@ -0,0 +1,46 @@
+package sort
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+)
+
+const randomSaltSize = 16
+
+func newRandomSalt() ([]byte, error) {
+	salt := make([]byte, randomSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+func SeedSalt(seed int64) []byte {
+	salt := make([]byte, 8)
+	binary.LittleEndian.PutUint64(salt, uint64(seed))
+	return salt
+}
+
+func ReadSalt(r io.Reader) ([]byte, error) {
+	salt := make([]byte, randomSaltSize)
+	if _, err := io.ReadFull(r, salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+func (s *Sorter) compareRandom(a, b string) int {
+	ha := s.randomHash(a)
+	hb := s.randomHash(b)
+	return bytes.Compare(ha[:], hb[:])
+}
+
+func (s *Sorter) randomHash(key string) [md5.Size]byte {
+	buf := make([]byte, 0, len(s.salt)+len(key))
+	buf = append(buf, s.salt...)
+	buf = append(buf, key...)
+	return md5.Sum(buf)
+}
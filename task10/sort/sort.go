@@ -1,24 +1,45 @@
 package sort
 
 import (
-	"flag"
 	"fmt"
-	"os"
+	"io"
 	"sort"
-	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
-var (
-	Column       = flag.Int("k", 0, "column sort")
-	Numeric      = flag.Bool("n", false, "numeric sort")
-	Reverse      = flag.Bool("r", false, "reverse order")
-	Unique       = flag.Bool("u", false, "unique lines")
-	MonthSort    = flag.Bool("M", false, "month sort")
-	IgnoreBlanks = flag.Bool("b", false, "ignore trailing blanks")
-	CheckOnly    = flag.Bool("c", false, "check if sorted")
-	HumanSort    = flag.Bool("h", false, "human-readable (10K, 2M)")
-)
+type Options struct {
+	Keys           []KeySpec
+	Numeric        bool
+	General        bool
+	Reverse        bool
+	Unique         bool
+	Count          bool
+	Month          bool
+	IgnoreBlanks   bool
+	Human          bool
+	Version        bool
+	Random         bool
+	RandomSalt     []byte
+	FoldCase       bool
+	Dictionary     bool
+	Locale         string
+	Separator      string
+	CSV            bool
+	Stable         bool
+	Debug          bool
+	Parallel       int
+	ZeroTerminated bool
+	BufferSize     int64
+	TempDir        string
+}
+
+type Sorter struct {
+	opts   Options
+	keys   []KeySpec
+	locale *collator
+	salt   []byte
+}
 
 var months = map[string]int{
 	"Jan": 1, "Feb": 2, "Mar": 3, "Apr": 4,
@@ -26,113 +47,224 @@ var months = map[string]int{
 	"Sep": 9, "Oct": 10, "Nov": 11, "Dec": 12,
 }
 
-func ProcessLines(lines []string) []string {
-	if *CheckOnly {
-		if isSorted(lines, less) {
-			fmt.Println("input is sorted")
-		} else {
-			fmt.Println("input is not sorted")
+func New(opts Options) (*Sorter, error) {
+	if opts.Separator != "" && utf8.RuneCountInString(opts.Separator) != 1 {
+		return nil, fmt.Errorf("separator must be a single character: %q", opts.Separator)
+	}
+	if opts.BufferSize < 0 {
+		return nil, fmt.Errorf("invalid buffer size: %d", opts.BufferSize)
+	}
+	if opts.BufferSize == 0 {
+		opts.BufferSize = defaultBufferSize
+	}
+
+	s := &Sorter{opts: opts}
+	if opts.Locale != "" {
+		c, err := newCollator(opts.Locale)
+		if err != nil {
+			return nil, err
 		}
-		os.Exit(0)
+		s.locale = c
 	}
 
-	sort.Slice(lines, func(i, j int) bool {
-		return less(lines[i], lines[j])
-	})
+	global := KeySpec{
+		StartField: 1,
+		StartChar:  1,
+		Numeric:    opts.Numeric,
+		General:    opts.General,
+		Month:      opts.Month,
+		Human:      opts.Human,
+		Reverse:    opts.Reverse,
+		Version:    opts.Version,
+		Random:     opts.Random,
+		FoldCase:   opts.FoldCase,
+		Dictionary: opts.Dictionary,
+	}
+	if len(opts.Keys) == 0 {
+		s.keys = []KeySpec{global}
+	}
+	for _, key := range opts.Keys {
+		if key.StartField <= 0 || key.StartChar <= 0 || key.EndField < 0 || key.EndChar < 0 {
+			return nil, fmt.Errorf("invalid key: %s", key)
+		}
+		if !key.hasOptions() {
+			key.Numeric = global.Numeric
+			key.General = global.General
+			key.Month = global.Month
+			key.Human = global.Human
+			key.Reverse = global.Reverse
+			key.Version = global.Version
+			key.Random = global.Random
+			key.FoldCase = global.FoldCase
+			key.Dictionary = global.Dictionary
+		}
+		s.keys = append(s.keys, key)
+	}
 
-	if *Unique {
-		lines = uniq(lines)
+	for _, key := range s.keys {
+		if !key.Random {
+			continue
+		}
+		s.salt = opts.RandomSalt
+		if len(s.salt) == 0 {
+			salt, err := newRandomSalt()
+			if err != nil {
+				return nil, err
+			}
+			s.salt = salt
+		}
+		break
 	}
 
-	return lines
+	return s, nil
 }
 
-func less(a, b string) bool {
-	ka := getKey(a)
-	kb := getKey(b)
+func (s *Sorter) SortLines(lines []string) []string {
+	lines = s.sortLines(lines)
+	if !s.unique() {
+		return lines
+	}
 
-	var res int
-	switch {
-	case *Numeric:
-		af, _ := strconv.ParseFloat(ka, 64)
-		bf, _ := strconv.ParseFloat(kb, 64)
-		if af < bf {
-			res = -1
-		} else if af > bf {
-			res = 1
+	var result []string
+	emit := s.newEmitter(func(line string, count int) error {
+		result = append(result, s.formatRecord(line, count))
+		return nil
+	})
+	for _, line := range lines {
+		_ = emit.emit(line)
+	}
+	_ = emit.flush()
+	return result
+}
+
+func (s *Sorter) unique() bool {
+	return s.opts.Unique || s.opts.Count
+}
+
+func (s *Sorter) stable() bool {
+	return s.opts.Stable || s.unique()
+}
+
+func (s *Sorter) Check(r io.Reader) (bool, int, error) {
+	records := newRecordReader(r, s.delim())
+	var prev string
+	n := 0
+	for {
+		line, ok, err := records.next()
+		if err != nil {
+			return false, 0, err
 		}
-	case *MonthSort:
-		res = compareInt(months[ka], months[kb])
-	case *HumanSort:
-		af := parseHuman(ka)
-		bf := parseHuman(kb)
-		res = compareInt(af, bf)
-	default:
-		res = strings.Compare(ka, kb)
+		if !ok {
+			break
+		}
+		line = s.trimRecord(line)
+		n++
+		if n > 1 {
+			res := s.compare(line, prev)
+			if res < 0 || (s.unique() && res == 0) {
+				return false, n, nil
+			}
+		}
+		prev = line
 	}
+	return true, 0, nil
+}
 
-	if *Reverse {
-		return res > 0
+func (s *Sorter) sortLines(lines []string) []string {
+	if s.opts.Parallel > 1 && len(lines) >= minParallelLines {
+		return s.parallelSort(lines, s.opts.Parallel)
 	}
-	return res < 0
+	return s.sequentialSort(lines)
 }
 
-func getKey(line string) string {
-	cols := strings.Split(line, "\t")
-	if *Column > 0 && *Column <= len(cols) {
-		return cols[*Column-1]
+func (s *Sorter) sequentialSort(lines []string) []string {
+	if s.stable() {
+		sort.SliceStable(lines, func(i, j int) bool {
+			return s.less(lines[i], lines[j])
+		})
+		return lines
 	}
-	return line
+	sort.Slice(lines, func(i, j int) bool {
+		return s.less(lines[i], lines[j])
+	})
+	return lines
 }
 
-func compareInt(a, b int) int {
-	switch {
-	case a < b:
-		return -1
-	case a > b:
-		return 1
-	default:
-		return 0
+func (s *Sorter) less(a, b string) bool {
+	return s.compare(a, b) < 0
+}
+
+func (s *Sorter) compare(a, b string) int {
+	if res := s.compareByKeys(a, b); res != 0 || s.stable() {
+		return res
 	}
+	return s.lastResort(a, b)
 }
 
-func parseHuman(s string) int {
-	if s == "" {
-		return 0
+func (s *Sorter) compareByKeys(a, b string) int {
+	if len(s.opts.Keys) == 0 {
+		return s.compareKeys(a, b, s.keys[0])
 	}
-	n := len(s)
-	unit := s[n-1]
-	val, _ := strconv.ParseFloat(s[:n-1], 64)
-	switch unit {
-	case 'K', 'k':
-		return int(val * 1024)
-	case 'M', 'm':
-		return int(val * 1024 * 1024)
-	case 'G', 'g':
-		return int(val * 1024 * 1024 * 1024)
-	default:
-		v, _ := strconv.Atoi(s)
-		return v
+	for _, key := range s.keys {
+		if res := s.compareKeys(s.getKey(a, key), s.getKey(b, key), key); res != 0 {
+			return res
+		}
 	}
+	return 0
 }
 
-func uniq(lines []string) []string {
-	var result []string
-	seen := make(map[string]struct{})
-	for _, l := range lines {
-		if _, ok := seen[l]; !ok {
-			result = append(result, l)
-			seen[l] = struct{}{}
-		}
+func (s *Sorter) lastResort(a, b string) int {
+	res := 0
+	if s.locale != nil {
+		res = s.locale.compare(a, b)
 	}
-	return result
+	if res == 0 {
+		res = strings.Compare(a, b)
+	}
+	if s.opts.Reverse {
+		return -res
+	}
+	return res
 }
 
-func isSorted(lines []string, cmp func(a, b string) bool) bool {
-	for i := 1; i < len(lines); i++ {
-		if cmp(lines[i], lines[i-1]) {
-			return false
-		}
+func (s *Sorter) compareKeys(ka, kb string, key KeySpec) int {
+	var res int
+	switch {
+	case key.Numeric:
+		a, _, _, _ := parseNumeric(ka)
+		b, _, _, _ := parseNumeric(kb)
+		res = compareFloat(a, b)
+	case key.General:
+		res = compareGeneral(ka, kb)
+	case key.Month:
+		a, _, _, _ := monthOf(ka)
+		b, _, _, _ := monthOf(kb)
+		res = compareInt(a, b)
+	case key.Human:
+		a, _, _, _ := parseHuman(ka)
+		b, _, _, _ := parseHuman(kb)
+		res = compareFloat(a, b)
+	case key.Version:
+		res = compareVersion(ka, kb)
+	case key.Random:
+		res = s.compareRandom(ka, kb)
+	default:
+		res = s.compareText(ka, kb, key)
+	}
+
+	if key.Reverse {
+		return -res
+	}
+	return res
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
 	}
-	return true
 }
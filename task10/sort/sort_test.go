@@ -1,29 +1,39 @@
 package sort
 
 import (
-	"flag"
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
-func resetFlags() {
-	flag.CommandLine = flag.NewFlagSet("", flag.ContinueOnError)
-	Column = flag.CommandLine.Int("k", 0, "")
-	Numeric = flag.CommandLine.Bool("n", false, "")
-	Reverse = flag.CommandLine.Bool("r", false, "")
-	Unique = flag.CommandLine.Bool("u", false, "")
-	MonthSort = flag.CommandLine.Bool("M", false, "")
-	IgnoreBlanks = flag.CommandLine.Bool("b", false, "")
-	CheckOnly = flag.CommandLine.Bool("c", false, "")
-	HumanSort = flag.CommandLine.Bool("h", false, "")
+func mustNew(t testing.TB, opts Options) *Sorter {
+	t.Helper()
+	s, err := New(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return s
+}
+
+func mustKey(t testing.TB, spec string) KeySpec {
+	t.Helper()
+	key, err := ParseKey(spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return key
 }
 
 func TestNumericSort(t *testing.T) {
-	resetFlags()
-	*Numeric = true
+	opts := Options{Numeric: true}
 
 	lines := []string{"10", "2", "1", "30"}
-	got := ProcessLines(lines)
+	got := mustNew(t, opts).SortLines(lines)
 	want := []string{"1", "2", "10", "30"}
 
 	if !reflect.DeepEqual(got, want) {
@@ -32,11 +42,10 @@ func TestNumericSort(t *testing.T) {
 }
 
 func TestReverseSort(t *testing.T) {
-	resetFlags()
-	*Reverse = true
+	opts := Options{Reverse: true}
 
 	lines := []string{"a", "c", "b"}
-	got := ProcessLines(lines)
+	got := mustNew(t, opts).SortLines(lines)
 	want := []string{"c", "b", "a"}
 
 	if !reflect.DeepEqual(got, want) {
@@ -45,16 +54,14 @@ func TestReverseSort(t *testing.T) {
 }
 
 func TestColumnSort(t *testing.T) {
-	resetFlags()
-	*Column = 2
-	*Numeric = true
+	opts := Options{Keys: []KeySpec{mustKey(t, "2")}, Numeric: true}
 
 	lines := []string{
 		"foo\t3",
 		"baz\t1",
 		"bar\t2",
 	}
-	got := ProcessLines(lines)
+	got := mustNew(t, opts).SortLines(lines)
 	want := []string{
 		"baz\t1",
 		"bar\t2",
@@ -66,12 +73,244 @@ func TestColumnSort(t *testing.T) {
 	}
 }
 
+func TestMultiKeySort(t *testing.T) {
+	var opts Options
+	for _, spec := range []string{"2,2n", "1,1r"} {
+		opts.Keys = append(opts.Keys, mustKey(t, spec))
+	}
+
+	lines := []string{
+		"a\t2\tx",
+		"b\t1\ty",
+		"c\t2\tz",
+		"a\t10\tw",
+	}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{
+		"b\t1\ty",
+		"c\t2\tz",
+		"a\t2\tx",
+		"a\t10\tw",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Multi-key sort: got %v, want %v", got, want)
+	}
+}
+
+func TestKeyCharOffsets(t *testing.T) {
+	opts := Options{Keys: []KeySpec{mustKey(t, "1.3,1.4")}}
+
+	lines := []string{"xxcb", "yyab", "zzca"}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{"yyab", "zzca", "xxcb"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Char offset sort: got %v, want %v", got, want)
+	}
+}
+
+func TestKeyInheritsGlobalOptions(t *testing.T) {
+	opts := Options{Keys: []KeySpec{mustKey(t, "1,1"), mustKey(t, "2,2n")}, Reverse: true}
+
+	lines := []string{"a\t2", "b\t1", "a\t10"}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{"b\t1", "a\t2", "a\t10"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Inherited options: got %v, want %v", got, want)
+	}
+}
+
+func TestSeparatorSort(t *testing.T) {
+	opts := Options{Keys: []KeySpec{mustKey(t, "3,3n")}, Separator: ":"}
+
+	lines := []string{
+		"root:x:0:0",
+		"user:x:1000:1000",
+		"daemon:x:1:1",
+	}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{
+		"root:x:0:0",
+		"daemon:x:1:1",
+		"user:x:1000:1000",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Separator sort: got %v, want %v", got, want)
+	}
+}
+
+func TestBlankRunFields(t *testing.T) {
+	opts := Options{Keys: []KeySpec{mustKey(t, "2,2n")}}
+
+	lines := []string{
+		"  root    120  bash",
+		"  www      15  nginx",
+		"  user   3000  vim",
+	}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{
+		"  www      15  nginx",
+		"  root    120  bash",
+		"  user   3000  vim",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Blank-run sort: got %v, want %v", got, want)
+	}
+}
+
+func TestCSVSort(t *testing.T) {
+	opts := Options{Keys: []KeySpec{mustKey(t, "2,2")}, CSV: true}
+
+	lines := []string{
+		`1,"Smith, John",x`,
+		`2,Adams,y`,
+		`3,"""Quoted"" Name",z`,
+	}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{
+		`3,"""Quoted"" Name",z`,
+		`2,Adams,y`,
+		`1,"Smith, John",x`,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CSV sort: got %v, want %v", got, want)
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		sep      string
+		csv      bool
+		expected []string
+	}{
+		{"blank runs", " a  b\tc", "", false, []string{" a", "  b", "\tc"}},
+		{"separator", "a::b", ":", false, []string{"a", "", "b"}},
+		{"tab", "a\tb c", "\t", false, []string{"a", "b c"}},
+		{"csv quoted", `a,"b,c",d`, "", true, []string{"a", "b,c", "d"}},
+		{"csv semicolon", `a;"b;c"`, ";", true, []string{"a", "b;c"}},
+		{"csv empty", `,`, "", true, []string{"", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mustNew(t, Options{CSV: tt.csv, Separator: tt.sep})
+			var got []string
+			for _, f := range s.splitFields(tt.line) {
+				got = append(got, tt.line[f.start:f.end])
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLastResortComparison(t *testing.T) {
+	opts := Options{Keys: []KeySpec{mustKey(t, "2,2n")}}
+
+	lines := []string{"c 1", "a 2", "b 1", "a 1"}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{"a 1", "b 1", "c 1", "a 2"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Last-resort sort: got %v, want %v", got, want)
+	}
+}
+
+func TestStableSort(t *testing.T) {
+	opts := Options{Keys: []KeySpec{mustKey(t, "2,2n")}, Stable: true}
+
+	lines := []string{"c 1", "a 2", "b 1", "a 1", "d 2"}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{"c 1", "b 1", "a 1", "a 2", "d 2"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Stable sort: got %v, want %v", got, want)
+	}
+}
+
+func TestSortStableAcrossRuns(t *testing.T) {
+	opts := Options{Keys: []KeySpec{mustKey(t, "1,1n")}, Stable: true, BufferSize: 32, TempDir: t.TempDir()}
+
+	var in, want strings.Builder
+	for i := 0; i < 150; i++ {
+		fmt.Fprintf(&in, "%d %d\n", i%3, i)
+	}
+	for k := 0; k < 3; k++ {
+		for i := k; i < 150; i += 3 {
+			fmt.Fprintf(&want, "%d %d\n", k, i)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := mustNew(t, opts).Sort(strings.NewReader(in.String()), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != want.String() {
+		t.Errorf("stable external sort: got %q, want %q", out.String(), want.String())
+	}
+}
+
+func TestParallelSort(t *testing.T) {
+	opts := Options{Keys: []KeySpec{mustKey(t, "1,1n")}, Parallel: 4, Stable: true}
+
+	lines := make([]string, 5000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%d %d", (len(lines)-i)%10, i)
+	}
+	want := make([]string, len(lines))
+	copy(want, lines)
+	opts.Parallel = 1
+	want = mustNew(t, opts).SortLines(want)
+
+	opts.Parallel = 4
+	got := mustNew(t, opts).SortLines(lines)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parallel sort differs from sequential sort")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected KeySpec
+		hasError bool
+	}{
+		{"2", KeySpec{StartField: 2, StartChar: 1}, false},
+		{"2,2n", KeySpec{StartField: 2, StartChar: 1, EndField: 2, Numeric: true}, false},
+		{"3.4,3.8", KeySpec{StartField: 3, StartChar: 4, EndField: 3, EndChar: 8}, false},
+		{"1br,1", KeySpec{StartField: 1, StartChar: 1, EndField: 1, StartBlanks: true, Reverse: true}, false},
+		{"0", KeySpec{}, true},
+		{"a", KeySpec{}, true},
+		{"1x", KeySpec{}, true},
+		{"1,", KeySpec{StartField: 1, StartChar: 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseKey(tt.spec)
+			if (err != nil) != tt.hasError {
+				t.Errorf("expected error=%v, got %v", tt.hasError, err)
+			}
+			if !tt.hasError && got != tt.expected {
+				t.Errorf("got %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestMonthSort(t *testing.T) {
-	resetFlags()
-	*MonthSort = true
+	opts := Options{Month: true}
 
 	lines := []string{"Mar", "Jan", "Feb"}
-	got := ProcessLines(lines)
+	got := mustNew(t, opts).SortLines(lines)
 	want := []string{"Jan", "Feb", "Mar"}
 
 	if !reflect.DeepEqual(got, want) {
@@ -80,24 +319,215 @@ func TestMonthSort(t *testing.T) {
 }
 
 func TestHumanSort(t *testing.T) {
-	resetFlags()
-	*HumanSort = true
+	opts := Options{Human: true}
 
-	lines := []string{"10K", "2M", "512"}
-	got := ProcessLines(lines)
-	want := []string{"512", "10K", "2M"}
+	lines := []string{"10K", "2m", "2M", "512", "3"}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{"3", "512", "10K", "2M", "2m"}
 
 	if !reflect.DeepEqual(got, want) {
 		t.Errorf("Human sort: got %v, want %v", got, want)
 	}
 }
 
+func TestNumericParsing(t *testing.T) {
+	opts := Options{Numeric: true, Stable: true}
+
+	lines := []string{"1,500 apples", "  -3.5", "abc", "200 pears", "1,50", "12,345,678"}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{"  -3.5", "abc", "1,50", "200 pears", "1,500 apples", "12,345,678"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Numeric parsing: got %v, want %v", got, want)
+	}
+}
+
+func TestGeneralNumericSort(t *testing.T) {
+	opts := Options{General: true}
+
+	lines := []string{"1e3", "NaN", "+inf", "-inf", "junk", "2.5E-1", "-7", "1e999", "2e99", "-1e999"}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{"junk", "NaN", "-1e999", "-inf", "-7", "2.5E-1", "1e3", "2e99", "+inf", "1e999"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("General numeric sort: got %v, want %v", got, want)
+	}
+}
+
+func TestParseHuman(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected float64
+		ok       bool
+	}{
+		{"512", 512, true},
+		{"1.5K", 1536, true},
+		{"2k", 2048, true},
+		{"1KiB", 1024, true},
+		{"1kB", 1000, true},
+		{"3MB", 3e6, true},
+		{"2m", 2 << 20, true},
+		{"1g", 1 << 30, true},
+		{"1t", 1 << 40, true},
+		{" 1T", 1 << 40, true},
+		{"1P", 1 << 50, true},
+		{"2E", 2 << 60, true},
+		{"1,024", 1024, true},
+		{"G", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, _, _, ok := parseHuman(tt.in)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("parseHuman(%q) = (%v, %v), want (%v, %v)", tt.in, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDebugOutput(t *testing.T) {
+	opts := Options{Keys: []KeySpec{mustKey(t, "2,2n")}, Debug: true}
+
+	var out bytes.Buffer
+	if err := mustNew(t, opts).Sort(strings.NewReader("b 20\na x\n"), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "a x\n" +
+		"  ^ no match for key\n" +
+		"___\n" +
+		"b 20\n" +
+		"  __\n" +
+		"____\n"
+	if out.String() != want {
+		t.Errorf("debug output: got %q, want %q", out.String(), want)
+	}
+}
+
+func TestRandomSort(t *testing.T) {
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, fmt.Sprintf("%d\tx%d", i%10, i))
+	}
+	opts := Options{Keys: []KeySpec{mustKey(t, "1,1R")}, Stable: true, RandomSalt: SeedSalt(7)}
+
+	first := mustNew(t, opts).SortLines(append([]string(nil), lines...))
+	second := mustNew(t, opts).SortLines(append([]string(nil), lines...))
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("same salt produced different orders")
+	}
+
+	seen := make(map[string]bool)
+	prev := ""
+	for _, line := range first {
+		key := strings.SplitN(line, "\t", 2)[0]
+		if key != prev && seen[key] {
+			t.Fatalf("lines with key %q are not adjacent: %v", key, first)
+		}
+		seen[key] = true
+		prev = key
+	}
+
+	opts.RandomSalt = SeedSalt(8)
+	other := mustNew(t, opts).SortLines(append([]string(nil), lines...))
+	if reflect.DeepEqual(first, other) {
+		t.Errorf("different salts produced the same order")
+	}
+}
+
+func TestReadSalt(t *testing.T) {
+	if _, err := ReadSalt(strings.NewReader("short")); err == nil {
+		t.Errorf("expected error for short random source")
+	}
+	salt, err := ReadSalt(strings.NewReader(strings.Repeat("r", 32)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(salt) != randomSaltSize {
+		t.Errorf("got %d salt bytes, want %d", len(salt), randomSaltSize)
+	}
+}
+
+func TestVersionSort(t *testing.T) {
+	opts := Options{Version: true}
+
+	lines := []string{"file10", "file2", "file1.10", "file1.2", "file1~rc1", "file1"}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{"file1~rc1", "file1", "file1.2", "file1.10", "file2", "file10"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Version sort: got %v, want %v", got, want)
+	}
+}
+
+func TestFoldCase(t *testing.T) {
+	opts := Options{FoldCase: true, Stable: true}
+
+	lines := []string{"b", "B", "a", "Яблоко", "абрикос", "A"}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{"a", "A", "b", "B", "абрикос", "Яблоко"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Fold case: got %v, want %v", got, want)
+	}
+}
+
+func TestDictionaryOrder(t *testing.T) {
+	opts := Options{Dictionary: true}
+
+	lines := []string{"c-1", "#b", "a.2"}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{"a.2", "#b", "c-1"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Dictionary order: got %v, want %v", got, want)
+	}
+}
+
+func TestLocaleCollation(t *testing.T) {
+	opts := Options{Locale: "de"}
+
+	lines := []string{"Zebra", "Äpfel", "apfel", "zebra", "Bär"}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{"apfel", "Äpfel", "Bär", "zebra", "Zebra"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Locale collation: got %v, want %v", got, want)
+	}
+
+	if _, err := New(Options{Locale: "not a locale"}); err == nil {
+		t.Errorf("expected error for invalid locale tag")
+	}
+}
+
+func TestCompareVersion(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2", "1.10", -1},
+		{"1.02", "1.2", 0},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0a", "1.0", 1},
+		{"a10", "a9", 1},
+		{"1.0-1", "1.0a", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			if got := compareVersion(tt.a, tt.b); got != tt.expected {
+				t.Errorf("compareVersion(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestUnique(t *testing.T) {
-	resetFlags()
-	*Unique = true
+	opts := Options{Unique: true}
 
 	lines := []string{"a", "b", "a", "c", "b"}
-	got := ProcessLines(lines)
+	got := mustNew(t, opts).SortLines(lines)
 	want := []string{"a", "b", "c"}
 
 	if !reflect.DeepEqual(got, want) {
@@ -105,13 +535,264 @@ func TestUnique(t *testing.T) {
 	}
 }
 
-func TestIsSorted(t *testing.T) {
-	lines := []string{"1", "2", "3"}
-	if !isSorted(lines, less) {
-		t.Errorf("expected sorted, but got not sorted")
+func TestUniqueByKey(t *testing.T) {
+	opts := Options{Keys: []KeySpec{mustKey(t, "2,2")}, Unique: true}
+
+	lines := []string{"x b", "y a", "z b", "w a", "v c"}
+	got := mustNew(t, opts).SortLines(lines)
+	want := []string{"y a", "x b", "v c"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unique by key: got %v, want %v", got, want)
+	}
+}
+
+func TestCount(t *testing.T) {
+	opts := Options{Keys: []KeySpec{mustKey(t, "1,1f")}, Count: true, BufferSize: 16, TempDir: t.TempDir()}
+
+	var out bytes.Buffer
+	err := mustNew(t, opts).Sort(strings.NewReader("b 1\nA 2\na 3\nB 4\nc 5\nb 6\n"), &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "      2 A 2\n      3 b 1\n      1 c 5\n"
+	if out.String() != want {
+		t.Errorf("count: got %q, want %q", out.String(), want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := mustNew(t, Options{})
+
+	ok, line, err := s.Check(strings.NewReader("1\n2\n3\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok || line != 0 {
+		t.Errorf("expected sorted, got ok=%v line=%d", ok, line)
+	}
+
+	ok, line, err = s.Check(strings.NewReader("1\n3\n2\n4\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok || line != 3 {
+		t.Errorf("expected disorder at line 3, got ok=%v line=%d", ok, line)
+	}
+
+	s = mustNew(t, Options{Unique: true})
+	if ok, line, _ := s.Check(strings.NewReader("a\nb\nb\n")); ok || line != 3 {
+		t.Errorf("expected duplicate at line 3 with unique, got ok=%v line=%d", ok, line)
+	}
+
+	s = mustNew(t, Options{Keys: []KeySpec{mustKey(t, "1,1")}, Unique: true})
+	if ok, line, _ := s.Check(strings.NewReader("a 1\nb 1\nb 2\n")); ok || line != 3 {
+		t.Errorf("expected equal key at line 3 with unique, got ok=%v line=%d", ok, line)
+	}
+}
+
+func TestIndependentSorters(t *testing.T) {
+	numeric := mustNew(t, Options{Numeric: true})
+	reverse := mustNew(t, Options{Reverse: true})
+
+	if got := numeric.SortLines([]string{"10", "9"}); !reflect.DeepEqual(got, []string{"9", "10"}) {
+		t.Errorf("numeric sorter: got %v", got)
+	}
+	if got := reverse.SortLines([]string{"10", "9"}); !reflect.DeepEqual(got, []string{"9", "10"}) {
+		t.Errorf("reverse sorter: got %v", got)
+	}
+	if got := numeric.SortLines([]string{"2", "1"}); !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("numeric sorter after reverse: got %v", got)
+	}
+}
+
+func TestSortSpillsRuns(t *testing.T) {
+	opts := Options{Numeric: true, BufferSize: 32, TempDir: t.TempDir()}
+
+	var in strings.Builder
+	for i := 200; i > 0; i-- {
+		fmt.Fprintln(&in, i)
+	}
+
+	var out bytes.Buffer
+	if err := mustNew(t, opts).Sort(strings.NewReader(in.String()), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want strings.Builder
+	for i := 1; i <= 200; i++ {
+		fmt.Fprintln(&want, i)
+	}
+	if out.String() != want.String() {
+		t.Errorf("external sort: got %q, want %q", out.String(), want.String())
+	}
+
+	entries, err := os.ReadDir(opts.TempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected temp runs to be removed, found %d files", len(entries))
+	}
+}
+
+func TestSortConcatInputs(t *testing.T) {
+	opts := Options{Unique: true}
+
+	inputs := []io.Reader{
+		strings.NewReader("c\na"),
+		strings.NewReader("b\na\n"),
+	}
+	var out bytes.Buffer
+	if err := mustNew(t, opts).Sort(Concat('\n', inputs...), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "a\nb\nc\n"
+	if out.String() != want {
+		t.Errorf("multiple inputs: got %q, want %q", out.String(), want)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected int64
+		hasError bool
+	}{
+		{"", 0, true},
+		{"100b", 100, false},
+		{"2", 2048, false},
+		{"10K", 10 << 10, false},
+		{"5M", 5 << 20, false},
+		{"1G", 1 << 30, false},
+		{"0", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSize(tt.spec)
+			if (err != nil) != tt.hasError {
+				t.Errorf("expected error=%v, got %v", tt.hasError, err)
+			}
+			if got != tt.expected {
+				t.Errorf("got %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func benchmarkLines(n int) []string {
+	rnd := rand.New(rand.NewSource(1))
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%08x\t%d", rnd.Uint32(), rnd.Intn(1000))
+	}
+	return lines
+}
+
+func BenchmarkSortLines(b *testing.B) {
+	s := mustNew(b, Options{})
+	input := benchmarkLines(200000)
+	lines := make([]string, len(input))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(lines, input)
+		s.SortLines(lines)
+	}
+}
+
+func BenchmarkParallelSort(b *testing.B) {
+	input := benchmarkLines(200000)
+	lines := make([]string, len(input))
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("parallel=%d", workers), func(b *testing.B) {
+			s := mustNew(b, Options{Parallel: workers})
+			for i := 0; i < b.N; i++ {
+				copy(lines, input)
+				s.SortLines(lines)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	s := mustNew(t, Options{Keys: []KeySpec{mustKey(t, "1,1n")}})
+
+	inputs := []io.Reader{
+		strings.NewReader("1 a\n4 a\n7 a\n"),
+		strings.NewReader("2 b\n4 b\n"),
+		strings.NewReader(""),
+		strings.NewReader("3 c\n9 c"),
+	}
+	var out bytes.Buffer
+	if err := s.Merge(inputs, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "1 a\n2 b\n3 c\n4 a\n4 b\n7 a\n9 c\n"
+	if out.String() != want {
+		t.Errorf("merge: got %q, want %q", out.String(), want)
+	}
+}
+
+func TestMergeUnsortedInput(t *testing.T) {
+	s := mustNew(t, Options{})
+
+	inputs := []io.Reader{
+		strings.NewReader("a\nc\n"),
+		strings.NewReader("b\nd\nc\n"),
+	}
+	err := s.Merge(inputs, io.Discard)
+	if err == nil {
+		t.Fatalf("expected error for unsorted input")
+	}
+	if !strings.Contains(err.Error(), "input 2") || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLongRecords(t *testing.T) {
+	long := strings.Repeat("x", 200000)
+	input := "b\n" + long + "\na"
+
+	var out bytes.Buffer
+	if err := mustNew(t, Options{}).Sort(strings.NewReader(input), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "a\nb\n" + long + "\n"
+	if out.String() != want {
+		t.Errorf("long records: got %d bytes, want %d bytes", out.Len(), len(want))
+	}
+}
+
+func TestZeroTerminated(t *testing.T) {
+	opts := Options{ZeroTerminated: true, BufferSize: 8, TempDir: t.TempDir()}
+	input := "c\nfile\x00a file\x00b\x00"
+
+	var out bytes.Buffer
+	if err := mustNew(t, opts).Sort(strings.NewReader(input), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "a file\x00b\x00c\nfile\x00"
+	if out.String() != want {
+		t.Errorf("zero terminated: got %q, want %q", out.String(), want)
+	}
+}
+
+func TestConcat(t *testing.T) {
+	r := Concat(0, strings.NewReader("a"), strings.NewReader("b\x00"), strings.NewReader(""), strings.NewReader("c"))
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	lines = []string{"3", "1", "2"}
-	if isSorted(lines, less) {
-		t.Errorf("expected not sorted, but got sorted")
+	if string(got) != "a\x00b\x00c\x00" {
+		t.Errorf("concat: got %q", got)
 	}
 }
@@ -0,0 +1,152 @@
+package sort
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+type collator struct {
+	pool *sync.Pool
+}
+
+func newCollator(locale string) (*collator, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, err
+	}
+	return &collator{
+		pool: &sync.Pool{
+			New: func() interface{} {
+				return collate.New(tag)
+			},
+		},
+	}, nil
+}
+
+func (c *collator) compare(a, b string) int {
+	col := c.pool.Get().(*collate.Collator)
+	defer c.pool.Put(col)
+	return col.CompareString(a, b)
+}
+
+func (s *Sorter) compareText(a, b string, key KeySpec) int {
+	if s.locale != nil {
+		if key.FoldCase || key.Dictionary {
+			a, b = filterText(a, key), filterText(b, key)
+		}
+		return s.locale.compare(a, b)
+	}
+	if !key.FoldCase && !key.Dictionary {
+		return strings.Compare(a, b)
+	}
+
+	for {
+		ra, sa := nextTextRune(a, key)
+		rb, sb := nextTextRune(b, key)
+		switch {
+		case sa == 0 && sb == 0:
+			return 0
+		case sa == 0:
+			return -1
+		case sb == 0:
+			return 1
+		case ra != rb:
+			if ra < rb {
+				return -1
+			}
+			return 1
+		}
+		a, b = a[sa:], b[sb:]
+	}
+}
+
+func nextTextRune(s string, key KeySpec) (rune, int) {
+	skipped := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if key.Dictionary && !isDictionaryRune(r) {
+			s = s[size:]
+			skipped += size
+			continue
+		}
+		if key.FoldCase {
+			r = unicode.ToUpper(r)
+		}
+		return r, skipped + size
+	}
+	return 0, 0
+}
+
+func filterText(s string, key KeySpec) string {
+	return strings.Map(func(r rune) rune {
+		if key.Dictionary && !isDictionaryRune(r) {
+			return -1
+		}
+		if key.FoldCase {
+			return unicode.ToUpper(r)
+		}
+		return r
+	}, s)
+}
+
+func isDictionaryRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ' || r == '\t'
+}
+
+func compareVersion(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			oa, ob := versionOrder(a), versionOrder(b)
+			if oa != ob {
+				return compareInt(oa, ob)
+			}
+			a, b = a[1:], b[1:]
+		}
+
+		a = strings.TrimLeft(a, "0")
+		b = strings.TrimLeft(b, "0")
+		res := 0
+		for a != "" && isDigit(a[0]) && b != "" && isDigit(b[0]) {
+			if res == 0 {
+				res = compareInt(int(a[0]), int(b[0]))
+			}
+			a, b = a[1:], b[1:]
+		}
+		if a != "" && isDigit(a[0]) {
+			return 1
+		}
+		if b != "" && isDigit(b[0]) {
+			return -1
+		}
+		if res != 0 {
+			return res
+		}
+	}
+	return 0
+}
+
+func versionOrder(s string) int {
+	if s == "" {
+		return 0
+	}
+	c := s[0]
+	switch {
+	case isDigit(c):
+		return 0
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -1 +1,8 @@
 module task12
+
+go 1.25.0
+
+require (
+	github.com/dlclark/regexp2 v1.12.0
+	github.com/klauspost/compress v1.20.1
+)
@@ -0,0 +1,101 @@
+package grep
+
+type ahoCorasick struct {
+	next  []map[byte]int
+	fail  []int
+	out   [][]int
+	fold  bool
+	empty bool
+}
+
+func newAhoCorasick(patterns []string, fold bool) *ahoCorasick {
+	ac := &ahoCorasick{fold: fold}
+	ac.addNode()
+	for _, p := range patterns {
+		if p == "" {
+			ac.empty = true
+			continue
+		}
+		state := 0
+		for i := 0; i < len(p); i++ {
+			c := ac.byteAt(p, i)
+			child, ok := ac.next[state][c]
+			if !ok {
+				child = ac.addNode()
+				ac.next[state][c] = child
+			}
+			state = child
+		}
+		ac.out[state] = appendLength(ac.out[state], len(p))
+	}
+
+	queue := make([]int, 0, len(ac.next))
+	for _, child := range ac.next[0] {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for c, child := range ac.next[state] {
+			f := ac.fail[state]
+			for f != 0 && !ac.has(f, c) {
+				f = ac.fail[f]
+			}
+			if target, ok := ac.next[f][c]; ok && target != child {
+				ac.fail[child] = target
+			}
+			for _, l := range ac.out[ac.fail[child]] {
+				ac.out[child] = appendLength(ac.out[child], l)
+			}
+			queue = append(queue, child)
+		}
+	}
+	return ac
+}
+
+func (ac *ahoCorasick) addNode() int {
+	ac.next = append(ac.next, make(map[byte]int))
+	ac.fail = append(ac.fail, 0)
+	ac.out = append(ac.out, nil)
+	return len(ac.next) - 1
+}
+
+func (ac *ahoCorasick) has(state int, c byte) bool {
+	_, ok := ac.next[state][c]
+	return ok
+}
+
+func (ac *ahoCorasick) byteAt(s string, i int) byte {
+	c := s[i]
+	if ac.fold && c >= 'A' && c <= 'Z' {
+		c += 'a' - 'A'
+	}
+	return c
+}
+
+func (ac *ahoCorasick) find(s string) [][]int {
+	var spans [][]int
+	state := 0
+	for i := 0; i < len(s); i++ {
+		c := ac.byteAt(s, i)
+		for state != 0 && !ac.has(state, c) {
+			state = ac.fail[state]
+		}
+		if child, ok := ac.next[state][c]; ok {
+			state = child
+		}
+		for _, l := range ac.out[state] {
+			spans = append(spans, []int{i + 1 - l, i + 1})
+		}
+	}
+	return spans
+}
+
+func appendLength(lengths []int, l int) []int {
+	for _, existing := range lengths {
+		if existing == l {
+			return lengths
+		}
+	}
+	return append(lengths, l)
+}
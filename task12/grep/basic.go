@@ -0,0 +1,74 @@
+package grep
+
+import "strings"
+
+func translateBasic(p string) string {
+	var b strings.Builder
+	atStart := true
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		wasStart := atStart
+		atStart = false
+		switch {
+		case c == '\\' && i+1 < len(p):
+			i++
+			switch n := p[i]; n {
+			case '(', '|':
+				b.WriteByte(n)
+				atStart = true
+			case ')', '{', '}', '+', '?':
+				b.WriteByte(n)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(n)
+			}
+		case c == '[':
+			end := bracketEnd(p, i)
+			b.WriteString(strings.ReplaceAll(p[i:end], `\`, `\\`))
+			i = end - 1
+		case c == '*' && wasStart:
+			b.WriteString(`\*`)
+		case c == '^':
+			if !wasStart {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(c)
+			atStart = wasStart
+		case c == '$' && !atEnd(p, i+1):
+			b.WriteString(`\$`)
+		case strings.IndexByte("(){}|+?", c) >= 0:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func bracketEnd(p string, i int) int {
+	j := i + 1
+	if j < len(p) && p[j] == '^' {
+		j++
+	}
+	if j < len(p) && p[j] == ']' {
+		j++
+	}
+	for j < len(p) && p[j] != ']' {
+		if p[j] == '[' && j+1 < len(p) && strings.IndexByte(":.=", p[j+1]) >= 0 {
+			if k := strings.Index(p[j+2:], string(p[j+1])+"]"); k >= 0 {
+				j += k + 4
+				continue
+			}
+		}
+		j++
+	}
+	if j < len(p) {
+		return j + 1
+	}
+	return len(p)
+}
+
+func atEnd(p string, i int) bool {
+	return i == len(p) || strings.HasPrefix(p[i:], `\)`) || strings.HasPrefix(p[i:], `\|`)
+}
@@ -0,0 +1,68 @@
+package grep
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDecompress(t *testing.T) {
+	const data = "alpha\nneedle one\n"
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, _ = gw.Write([]byte(data))
+	_ = gw.Close()
+
+	var zst bytes.Buffer
+	zw, err := zstd.NewWriter(&zst)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _ = zw.Write([]byte(data))
+	_ = zw.Close()
+
+	bz2 := []byte{
+		0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xdf, 0xf3,
+		0x76, 0x4d, 0x00, 0x00, 0x02, 0x51, 0x80, 0x00, 0x10, 0x40, 0x00, 0x26,
+		0x45, 0xc0, 0x00, 0x20, 0x00, 0x31, 0x00, 0x30, 0x20, 0xd0, 0x62, 0x65,
+		0xd6, 0xd8, 0x24, 0x06, 0x8b, 0x2d, 0x78, 0xbb, 0x92, 0x29, 0xc2, 0x84,
+		0x86, 0xff, 0x9b, 0xb2, 0x68,
+	}
+
+	inputs := map[string][]byte{
+		"plain": []byte(data),
+		"gzip":  gz.Bytes(),
+		"zstd":  zst.Bytes(),
+		"bzip2": bz2,
+	}
+	for name, input := range inputs {
+		rc, err := Decompress(bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		var out strings.Builder
+		_, err = Run(rc, &out, Options{Pattern: "needle", ShowNum: true, FileName: "log." + name, WithFileName: true})
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if want := "log." + name + ":2:needle one\n"; out.String() != want {
+			t.Errorf("%s: got %q, want %q", name, out.String(), want)
+		}
+	}
+}
+
+func TestDecompressEmpty(t *testing.T) {
+	rc, err := Decompress(strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b, _ := io.ReadAll(rc); len(b) != 0 {
+		t.Errorf("got %q", b)
+	}
+}
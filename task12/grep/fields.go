@@ -0,0 +1,240 @@
+package grep
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var errSkipLine = errors.New("line skipped")
+
+type FieldFilter struct {
+	Path  []string
+	Op    byte
+	Value string
+}
+
+func ParseFieldFilter(s string) (FieldFilter, error) {
+	var f FieldFilter
+	path := s
+	if i := strings.IndexAny(s, "=~"); i >= 0 {
+		path, f.Op, f.Value = s[:i], s[i], s[i+1:]
+	}
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return f, fmt.Errorf("invalid field filter: %q", s)
+	}
+	f.Path = strings.Split(path, ".")
+	return f, nil
+}
+
+func (f FieldFilter) String() string {
+	s := "." + strings.Join(f.Path, ".")
+	if f.Op != 0 {
+		s += string(f.Op) + f.Value
+	}
+	return s
+}
+
+type record interface {
+	field(path []string) (string, bool)
+}
+
+type jsonRecord map[string]interface{}
+
+type logfmtRecord map[string]string
+
+func fieldMatchFunc(opts Options, find matcher) (matcher, error) {
+	hasPattern := len(opts.patterns()) > 0
+	lineMustMatch := hasPattern
+	checks := make([]func(string) (bool, error), len(opts.Fields))
+	for i, f := range opts.Fields {
+		switch f.Op {
+		case '=':
+			value := f.Value
+			checks[i] = func(s string) (bool, error) {
+				if opts.IgnoreCase {
+					return strings.EqualFold(s, value), nil
+				}
+				return s == value, nil
+			}
+		case '~':
+			sub, err := patternMatchFunc(Options{
+				Patterns:     []string{f.Value},
+				Fixed:        opts.Fixed,
+				IgnoreCase:   opts.IgnoreCase,
+				Perl:         opts.Perl,
+				Basic:        opts.Basic,
+				MatchTimeout: opts.MatchTimeout,
+			})
+			if err != nil {
+				return nil, err
+			}
+			checks[i] = matches(sub)
+		default:
+			if !hasPattern {
+				return nil, fmt.Errorf("field filter %s needs a pattern", f)
+			}
+			checks[i] = matches(find)
+			lineMustMatch = false
+		}
+	}
+
+	return func(s string) ([][]int, error) {
+		rec, ok := parseRecord(s)
+		if !ok {
+			switch {
+			case opts.Unparsed != "pass":
+				return nil, errSkipLine
+			case hasPattern:
+				return find(s)
+			}
+			return [][]int{{0, 0}}, nil
+		}
+		for i, f := range opts.Fields {
+			value, ok := rec.field(f.Path)
+			if !ok {
+				return nil, errSkipLine
+			}
+			if matched, err := checks[i](value); err != nil || !matched {
+				return nil, err
+			}
+		}
+		spans, err := find(s)
+		if spans == nil && err == nil && !lineMustMatch {
+			spans = [][]int{{0, 0}}
+		}
+		return spans, err
+	}, nil
+}
+
+func matches(find matcher) func(string) (bool, error) {
+	return func(s string) (bool, error) {
+		spans, err := find(s)
+		return spans != nil, err
+	}
+}
+
+func parseRecord(s string) (record, bool) {
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{") {
+		dec := json.NewDecoder(strings.NewReader(trimmed))
+		dec.UseNumber()
+		var rec jsonRecord
+		if err := dec.Decode(&rec); err != nil || dec.More() {
+			return nil, false
+		}
+		return rec, true
+	}
+	rec, err := parseLogfmt(trimmed)
+	if err != nil {
+		return nil, false
+	}
+	return rec, true
+}
+
+func (r jsonRecord) field(path []string) (string, bool) {
+	var v interface{} = map[string]interface{}(r)
+	for _, key := range path {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			child, ok := node[key]
+			if !ok {
+				return "", false
+			}
+			v = child
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", false
+			}
+			v = node[i]
+		default:
+			return "", false
+		}
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case nil:
+		return "null", true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), true
+}
+
+func (r logfmtRecord) field(path []string) (string, bool) {
+	v, ok := r[strings.Join(path, ".")]
+	return v, ok
+}
+
+func parseLogfmt(s string) (logfmtRecord, error) {
+	rec := make(logfmtRecord)
+	pairs := 0
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ' ' && s[i] != '\t' {
+			if s[i] == '"' {
+				return nil, errors.New("logfmt: quote in key")
+			}
+			i++
+		}
+		key := s[start:i]
+		if i == len(s) || s[i] != '=' {
+			rec[key] = ""
+			continue
+		}
+		if key == "" {
+			return nil, errors.New("logfmt: empty key")
+		}
+		i++
+
+		if i < len(s) && s[i] == '"' {
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, errors.New("logfmt: unterminated quoted value")
+			}
+			value, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, err
+			}
+			rec[key] = value
+			i = end + 1
+		} else {
+			start = i
+			for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+				i++
+			}
+			rec[key] = s[start:i]
+		}
+		pairs++
+	}
+	if pairs == 0 {
+		return nil, errors.New("logfmt: no key=value pairs")
+	}
+	return rec, nil
+}
@@ -0,0 +1,106 @@
+package grep
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFieldFilter(t *testing.T) {
+	tests := []struct {
+		in   string
+		path string
+		op   byte
+		val  string
+	}{
+		{".level=error", "level", '=', "error"},
+		{"msg~time(out)?", "msg", '~', "time(out)?"},
+		{".http.status", "http.status", 0, ""},
+		{"a.b=x=y", "a.b", '=', "x=y"},
+	}
+	for _, tt := range tests {
+		f, err := ParseFieldFilter(tt.in)
+		if err != nil {
+			t.Fatalf("ParseFieldFilter(%q): unexpected error: %v", tt.in, err)
+		}
+		if strings.Join(f.Path, ".") != tt.path || f.Op != tt.op || f.Value != tt.val {
+			t.Errorf("ParseFieldFilter(%q) = %+v", tt.in, f)
+		}
+	}
+	for _, bad := range []string{"", ".", "=x"} {
+		if _, err := ParseFieldFilter(bad); err == nil {
+			t.Errorf("ParseFieldFilter(%q): expected error", bad)
+		}
+	}
+}
+
+func TestParseLogfmt(t *testing.T) {
+	rec, err := parseLogfmt(`ts=1 level=warn msg="disk \"sda\" full" http.status=507 debug`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"ts": "1", "level": "warn", "msg": `disk "sda" full`, "http.status": "507", "debug": ""}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Errorf("%s: got %q, want %q", k, rec[k], v)
+		}
+	}
+
+	for _, bad := range []string{"just some words", `msg="unterminated`, `"quoted"=key`} {
+		if _, err := parseLogfmt(bad); err == nil {
+			t.Errorf("parseLogfmt(%q): expected error", bad)
+		}
+	}
+}
+
+func TestFieldFiltering(t *testing.T) {
+	lines := []string{
+		`{"level":"error","msg":"db timeout","ctx":{"id":7,"tags":["a","b"]}}`,
+		`{"level":"INFO","msg":"timeout ok","ctx":{"id":8}}`,
+		`level=error msg="conn refused" id=9`,
+		`plain timeout text`,
+	}
+	field := func(spec string) FieldFilter {
+		f, err := ParseFieldFilter(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"equal", Options{Patterns: []string{}, Fields: []FieldFilter{field(".level=error")}, ShowNum: true}, "1:|3:"},
+		{"equal ignore case", Options{Patterns: []string{}, Fields: []FieldFilter{field(".level=info")}, IgnoreCase: true, ShowNum: true}, "2:"},
+		{"pattern on field", Options{Patterns: []string{"^time"}, Fields: []FieldFilter{field("msg")}, ShowNum: true}, "2:"},
+		{"regexp operator", Options{Patterns: []string{}, Fields: []FieldFilter{field("msg~out$")}, ShowNum: true}, "1:"},
+		{"nested and array", Options{Patterns: []string{}, Fields: []FieldFilter{field("ctx.id=7"), field("ctx.tags.1=b")}, ShowNum: true}, "1:"},
+		{"line must match pattern", Options{Patterns: []string{"refused"}, Fields: []FieldFilter{field("level=error")}, ShowNum: true}, "3:"},
+		{"invert", Options{Patterns: []string{}, Fields: []FieldFilter{field("level=error")}, Invert: true, ShowNum: true}, "2:"},
+		{"invert skips missing field", Options{Patterns: []string{}, Fields: []FieldFilter{field("ctx.id=7")}, Invert: true, ShowNum: true}, "2:"},
+		{"invert pass unparsed", Options{Patterns: []string{"timeout"}, Fields: []FieldFilter{field("level=error")}, Unparsed: "pass", Invert: true, ShowNum: true}, "2:|3:"},
+		{"pass unparsed", Options{Patterns: []string{"timeout"}, Fields: []FieldFilter{field("level=error")}, Unparsed: "pass", ShowNum: true}, "1:|4:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Process(lines, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var nums []string
+			for _, r := range result {
+				nums = append(nums, r[:strings.IndexAny(r, ":-")+1])
+			}
+			if got := strings.Join(nums, "|"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	_, err := Process(lines, Options{Patterns: []string{}, Fields: []FieldFilter{field("msg")}})
+	if err == nil {
+		t.Error("expected an error for a field filter without a pattern")
+	}
+}
@@ -0,0 +1,131 @@
+package grep
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type WalkOptions struct {
+	Recursive   bool
+	FollowLinks bool
+	Include     []string
+	Exclude     []string
+	ExcludeDir  []string
+}
+
+func Walk(paths []string, opts WalkOptions, fn func(path string, err error) error) error {
+	for _, path := range paths {
+		if path == "-" {
+			if err := fn(path, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			if err := fn(path, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if info.IsDir() {
+			if !opts.Recursive {
+				if err := fn(path, fmt.Errorf("%s: is a directory", path)); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := walkDir(path, opts, []os.FileInfo{info}, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if opts.includeFile(filepath.Base(path)) {
+			if err := fn(path, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func walkDir(dir string, opts WalkOptions, ancestors []os.FileInfo, fn func(string, error) error) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fn(dir, err)
+	}
+
+	for _, entry := range entries {
+		path := joinPath(dir, entry.Name())
+
+		var info os.FileInfo
+		if entry.Type()&os.ModeSymlink != 0 {
+			if !opts.FollowLinks {
+				continue
+			}
+			info, err = os.Stat(path)
+		} else {
+			info, err = entry.Info()
+		}
+		if err != nil {
+			if err := fn(path, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if info.IsDir() {
+			if matchAny(opts.ExcludeDir, entry.Name()) || isAncestor(ancestors, info) {
+				continue
+			}
+			if err := walkDir(path, opts, append(ancestors, info), fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !info.Mode().IsRegular() || !opts.includeFile(entry.Name()) {
+			continue
+		}
+		if err := fn(path, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func joinPath(dir, name string) string {
+	if os.IsPathSeparator(dir[len(dir)-1]) {
+		return dir + name
+	}
+	return dir + string(filepath.Separator) + name
+}
+
+func (o WalkOptions) includeFile(name string) bool {
+	if len(o.Include) > 0 && !matchAny(o.Include, name) {
+		return false
+	}
+	return !matchAny(o.Exclude, name)
+}
+
+func matchAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isAncestor(ancestors []os.FileInfo, info os.FileInfo) bool {
+	for _, a := range ancestors {
+		if os.SameFile(a, info) {
+			return true
+		}
+	}
+	return false
+}
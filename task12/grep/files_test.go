@@ -0,0 +1,153 @@
+package grep
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func collect(t *testing.T, paths []string, opts WalkOptions) []string {
+	t.Helper()
+	var found []string
+	err := Walk(paths, opts, func(path string, err error) error {
+		if err != nil {
+			return err
+		}
+		found = append(found, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Strings(found)
+	return found
+}
+
+func TestWalkRecursive(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"a.go":            "",
+		"b.txt":           "",
+		"sub/c.go":        "",
+		"vendor/d.go":     "",
+		"sub/deep/e.log":  "",
+		"sub/deep/f.go":   "",
+		".git/objects/ab": "",
+	})
+
+	tests := []struct {
+		name     string
+		opts     WalkOptions
+		expected []string
+	}{
+		{
+			name:     "all files",
+			opts:     WalkOptions{Recursive: true},
+			expected: []string{".git/objects/ab", "a.go", "b.txt", "sub/c.go", "sub/deep/e.log", "sub/deep/f.go", "vendor/d.go"},
+		},
+		{
+			name:     "include",
+			opts:     WalkOptions{Recursive: true, Include: []string{"*.go"}},
+			expected: []string{"a.go", "sub/c.go", "sub/deep/f.go", "vendor/d.go"},
+		},
+		{
+			name:     "exclude and exclude-dir",
+			opts:     WalkOptions{Recursive: true, Exclude: []string{"*.log"}, ExcludeDir: []string{"vendor", ".git"}},
+			expected: []string{"a.go", "b.txt", "sub/c.go", "sub/deep/f.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []string
+			for _, name := range tt.expected {
+				want = append(want, filepath.Join(root, name))
+			}
+			got := collect(t, []string{root}, tt.opts)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWalkKeepsRoot(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"a.txt": "", "sub/b.txt": ""})
+	t.Chdir(root)
+
+	tests := []struct {
+		root     string
+		expected []string
+	}{
+		{".", []string{"./a.txt", "./sub/b.txt"}},
+		{"./", []string{"./a.txt", "./sub/b.txt"}},
+		{"./sub/../sub", []string{"./sub/../sub/b.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.root, func(t *testing.T) {
+			got := collect(t, []string{tt.root}, WalkOptions{Recursive: true})
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWalkSymlinks(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"dir/a.txt": "", "other/b.txt": ""})
+	if err := os.Symlink(filepath.Join(root, "other"), filepath.Join(root, "dir", "link")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "dir"), filepath.Join(root, "dir", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(root, "dir")
+	got := collect(t, []string{dir}, WalkOptions{Recursive: true})
+	want := []string{filepath.Join(dir, "a.txt")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("-r: got %v, want %v", got, want)
+	}
+
+	got = collect(t, []string{dir}, WalkOptions{Recursive: true, FollowLinks: true})
+	want = []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "link", "b.txt")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("-R: got %v, want %v", got, want)
+	}
+}
+
+func TestWalkDirectoryWithoutRecursion(t *testing.T) {
+	root := t.TempDir()
+	var errs int
+	err := Walk([]string{root, "-"}, WalkOptions{}, func(path string, err error) error {
+		if err != nil {
+			errs++
+		} else if path != "-" {
+			t.Errorf("unexpected path %q", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if errs != 1 {
+		t.Errorf("expected one directory error, got %d", errs)
+	}
+}
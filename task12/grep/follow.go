@@ -0,0 +1,96 @@
+package grep
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+type followReader struct {
+	ctx      context.Context
+	path     string
+	interval time.Duration
+	f        *os.File
+	info     os.FileInfo
+	offset   int64
+}
+
+func Follow(ctx context.Context, path string, interval time.Duration) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &followReader{ctx: ctx, path: path, interval: interval, f: f, info: info}, nil
+}
+
+func (fr *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := fr.f.Read(p)
+		fr.offset += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		reopened, err := fr.reopen()
+		if err != nil {
+			return 0, err
+		}
+		if reopened {
+			continue
+		}
+		select {
+		case <-fr.ctx.Done():
+			return 0, io.EOF
+		case <-time.After(fr.interval):
+		}
+	}
+}
+
+func (fr *followReader) reopen() (bool, error) {
+	info, err := os.Stat(fr.path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !os.SameFile(fr.info, info) {
+		f, err := os.Open(fr.path)
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if info, err = f.Stat(); err != nil {
+			_ = f.Close()
+			return false, err
+		}
+		_ = fr.f.Close()
+		fr.f, fr.info, fr.offset = f, info, 0
+		return true, nil
+	}
+
+	if info.Size() < fr.offset {
+		if _, err := fr.f.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		fr.offset = 0
+		return true, nil
+	}
+	return false, nil
+}
+
+func (fr *followReader) Close() error {
+	return fr.f.Close()
+}
@@ -0,0 +1,93 @@
+package grep
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (s *syncBuilder) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuilder) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func TestFollowRotationAndTruncation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("a\nERR one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, err := Follow(ctx, path, 5*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	var out syncBuilder
+	done := make(chan error, 1)
+	go func() {
+		_, err := Run(r, &out, Options{Pattern: "ERR", Before: 1, ShowNum: true})
+		done <- err
+	}()
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !strings.HasSuffix(out.String(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %q, got %q", want, out.String())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	appendFile := func(name, data string) {
+		t.Helper()
+		f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = f.WriteString(data)
+		_ = f.Close()
+	}
+
+	waitFor("2:ERR one\n")
+	appendFile(path, "b\nERR two\n")
+	waitFor("3-b\n4:ERR two\n")
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(path, "c\nERR three\n")
+	waitFor("5-c\n6:ERR three\n")
+
+	if err := os.WriteFile(path, []byte("ERR four\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("7:ERR four\n")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -2,110 +2,293 @@ package grep
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
-	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Options struct {
-	After      int
-	Before     int
-	CountOnly  bool
-	IgnoreCase bool
-	Invert     bool
-	Fixed      bool
-	ShowNum    bool
-	Pattern    string
+	After             int
+	Before            int
+	CountOnly         bool
+	IgnoreCase        bool
+	Invert            bool
+	Fixed             bool
+	ShowNum           bool
+	Pattern           string
+	Patterns          []string
+	WordRegexp        bool
+	LineRegexp        bool
+	FileName          string
+	WithFileName      bool
+	FilesWithMatches  bool
+	FilesWithoutMatch bool
+	OnlyMatching      bool
+	ByteOffset        bool
+	Color             bool
+	Quiet             bool
+	MaxCount          int
+	JSON              bool
+	BinaryFiles       string
+	Perl              bool
+	Basic             bool
+	MatchTimeout      time.Duration
+	Fields            []FieldFilter
+	Unparsed          string
 }
 
-func matchFunc(pattern string, opts Options) (func(string) bool, error) {
-	if opts.Fixed {
-		if opts.IgnoreCase {
-			pattern = strings.ToLower(pattern)
-			return func(s string) bool {
-				return strings.Contains(strings.ToLower(s), pattern)
-			}, nil
+func ReadLines(r io.Reader) ([]string, error) {
+	br, _, err := decodeInput(r)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	next := readerSource(br)
+	for {
+		line, _, ok, err := next()
+		if err != nil {
+			return nil, err
 		}
-		return func(s string) bool {
-			return strings.Contains(s, pattern)
-		}, nil
+		if !ok {
+			return lines, nil
+		}
+		lines = append(lines, line)
 	}
+}
 
-	flags := ""
-	if opts.IgnoreCase {
-		flags = "(?i)"
-	}
-	re, err := regexp.Compile(flags + pattern)
+type Line struct {
+	Num     int
+	Offset  int64
+	Text    string
+	Match   bool
+	Matches [][]int
+}
+
+type Matcher struct {
+	opts Options
+	find matcher
+}
+
+func Compile(opts Options) (*Matcher, error) {
+	find, err := matchFunc(opts)
 	if err != nil {
 		return nil, err
 	}
-	return func(s string) bool {
-		return re.MatchString(s)
-	}, nil
+	return &Matcher{opts: opts, find: find}, nil
 }
 
-func ReadLines(r io.Reader) ([]string, error) {
-	var lines []string
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+func (m *Matcher) Search(r io.Reader, emit func(Line) error) (int, error) {
+	br, _, err := decodeInput(r)
+	if err != nil {
+		return 0, err
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	return search(readerSource(br), m.find, m.opts, emit)
+}
+
+func (m *Matcher) Run(r io.Reader, w io.Writer, fileName string) (int, error) {
+	br, binary, err := decodeInput(r)
+	if err != nil {
+		return 0, err
+	}
+	opts := m.opts
+	opts.FileName = fileName
+	return report(readerSource(br), binary, m.find, opts, func(s string) error {
+		_, err := io.WriteString(w, s+"\n")
+		return err
+	})
+}
+
+func Search(r io.Reader, opts Options, emit func(Line) error) (int, error) {
+	m, err := Compile(opts)
+	if err != nil {
+		return 0, err
+	}
+	return m.Search(r, emit)
+}
+
+func Run(r io.Reader, w io.Writer, opts Options) (int, error) {
+	m, err := Compile(opts)
+	if err != nil {
+		return 0, err
 	}
-	return lines, nil
+	return m.Run(r, w, opts.FileName)
 }
 
 func Process(lines []string, opts Options) ([]string, error) {
-	isMatch, err := matchFunc(opts.Pattern, opts)
+	find, err := matchFunc(opts)
 	if err != nil {
 		return nil, err
 	}
+	var result []string
+	_, err = report(sliceSource(lines), false, find, opts, func(s string) error {
+		result = append(result, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type lineSource func() (string, int, bool, error)
+
+var errBinaryMatch = errors.New("binary file matches")
+
+func readerSource(r io.Reader) lineSource {
+	br := bufio.NewReader(r)
+	return func() (string, int, bool, error) {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", 0, false, err
+		}
+		if line == "" && err == io.EOF {
+			return "", 0, false, nil
+		}
+		size := len(line)
+		line = strings.TrimSuffix(line, "\n")
+		return strings.TrimSuffix(line, "\r"), size, true, nil
+	}
+}
 
-	matches := make([]bool, len(lines))
-	for i, line := range lines {
-		ok := isMatch(line)
-		if opts.Invert {
-			ok = !ok
+func sliceSource(lines []string) lineSource {
+	return func() (string, int, bool, error) {
+		if len(lines) == 0 {
+			return "", 0, false, nil
 		}
-		matches[i] = ok
+		line := lines[0]
+		lines = lines[1:]
+		return line, len(line) + 1, true, nil
 	}
+}
 
-	if opts.CountOnly {
-		cnt := 0
-		for _, m := range matches {
-			if m {
-				cnt++
-			}
+func binarySource(next lineSource, stop bool, seen *bool) lineSource {
+	return func() (string, int, bool, error) {
+		text, size, ok, err := next()
+		if ok && strings.IndexByte(text, 0) >= 0 {
+			*seen = true
+		}
+		if *seen && stop {
+			return "", 0, false, nil
 		}
-		return []string{fmt.Sprintf("%d", cnt)}, nil
+		return text, size, ok, err
 	}
+}
 
-	printed := make([]bool, len(lines))
-	var result []string
-	for i, m := range matches {
-		if !m {
-			continue
-		}
-		start := i - opts.Before
-		if start < 0 {
-			start = 0
-		}
-		end := i + opts.After
-		if end >= len(lines) {
-			end = len(lines) - 1
-		}
-		for j := start; j <= end; j++ {
-			if !printed[j] {
-				if opts.ShowNum {
-					result = append(result, fmt.Sprintf("%d:%s", j+1, lines[j]))
-				} else {
-					result = append(result, lines[j])
+func search(next lineSource, find matcher, opts Options, emit func(Line) error) (int, error) {
+	firstOnly := opts.Quiet || opts.FilesWithMatches || opts.FilesWithoutMatch
+	quiet := firstOnly || opts.CountOnly
+	before := newRing(opts.Before)
+	afterLeft := 0
+	cnt := 0
+	var offset int64
+	for n := 1; ; n++ {
+		limited := opts.MaxCount > 0 && cnt >= opts.MaxCount
+		if limited && (quiet || afterLeft == 0) {
+			return cnt, nil
+		}
+
+		text, size, ok, err := next()
+		if err != nil {
+			return cnt, err
+		}
+		if !ok {
+			return cnt, nil
+		}
+
+		matches, err := find(text)
+		skip := err == errSkipLine
+		if err != nil && !skip {
+			return cnt, err
+		}
+		line := Line{Num: n, Offset: offset, Text: text, Matches: matches}
+		line.Match = !skip && (line.Matches != nil) != opts.Invert && !limited
+		offset += int64(size)
+		if line.Match {
+			cnt++
+		}
+		switch {
+		case quiet:
+			if line.Match && firstOnly {
+				return cnt, nil
+			}
+		case line.Match:
+			for _, ctx := range before.drain() {
+				if err := emit(ctx); err != nil {
+					return cnt, err
 				}
-				printed[j] = true
 			}
+			if err := emit(line); err != nil {
+				return cnt, err
+			}
+			afterLeft = opts.After
+		case afterLeft > 0:
+			if err := emit(line); err != nil {
+				return cnt, err
+			}
+			afterLeft--
+		default:
+			before.push(line)
 		}
 	}
-	return result, nil
+}
+
+func report(next lineSource, binary bool, find matcher, opts Options, out func(string) error) (int, error) {
+	late := false
+	json := opts.JSON && !opts.Quiet
+	stop := json || opts.BinaryFiles == "without-match"
+	switch {
+	case opts.BinaryFiles == "text":
+		binary = false
+	case !binary:
+		next = binarySource(next, stop, &late)
+	case stop:
+		next, binary = sliceSource(nil), false
+	}
+	if json {
+		return reportJSON(next, find, opts, out)
+	}
+
+	p := &printer{opts: opts, out: out}
+	binaryMatch := func() error {
+		return out(fmt.Sprintf("Binary file %s matches", opts.FileName))
+	}
+	if binary && !opts.Quiet && !opts.CountOnly && !opts.FilesWithMatches && !opts.FilesWithoutMatch {
+		opts.Quiet = true
+		cnt, err := search(next, find, opts, p.print)
+		if err == nil && cnt > 0 {
+			err = binaryMatch()
+		}
+		return cnt, err
+	}
+
+	cnt, err := search(next, find, opts, func(line Line) error {
+		switch {
+		case !late:
+			return p.print(line)
+		case !line.Match:
+			return nil
+		}
+		if err := binaryMatch(); err != nil {
+			return err
+		}
+		return errBinaryMatch
+	})
+	if err == errBinaryMatch {
+		return cnt, nil
+	}
+	if err != nil {
+		return cnt, err
+	}
+
+	switch {
+	case opts.Quiet:
+	case opts.FilesWithMatches && cnt > 0, opts.FilesWithoutMatch && cnt == 0:
+		err = out(p.paint(colorFileName, opts.FileName))
+	case opts.CountOnly && !opts.FilesWithMatches && !opts.FilesWithoutMatch:
+		err = out(p.prefix(':') + strconv.Itoa(cnt))
+	}
+	return cnt, err
 }
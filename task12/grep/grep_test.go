@@ -121,3 +121,265 @@ func TestReadLines(t *testing.T) {
 		}
 	}
 }
+
+func TestFileNamePrefix(t *testing.T) {
+	lines := []string{"alpha", "beta"}
+	opts := Options{Pattern: "beta", ShowNum: true, FileName: "a.txt", WithFileName: true}
+
+	result, err := Process(lines, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result) != 1 || result[0] != "a.txt:2:beta" {
+		t.Errorf("file name prefix failed: got %v", result)
+	}
+
+	opts.CountOnly = true
+	result, _ = Process(lines, opts)
+	if len(result) != 1 || result[0] != "a.txt:1" {
+		t.Errorf("count with file name failed: got %v", result)
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	lines := []string{"alpha", "beta"}
+
+	result, _ := Process(lines, Options{Pattern: "beta", FileName: "a.txt", FilesWithMatches: true})
+	if len(result) != 1 || result[0] != "a.txt" {
+		t.Errorf("-l failed: got %v", result)
+	}
+	result, _ = Process(lines, Options{Pattern: "gamma", FileName: "a.txt", FilesWithMatches: true})
+	if len(result) != 0 {
+		t.Errorf("-l without match failed: got %v", result)
+	}
+	result, _ = Process(lines, Options{Pattern: "gamma", FileName: "a.txt", FilesWithoutMatch: true})
+	if len(result) != 1 || result[0] != "a.txt" {
+		t.Errorf("-L failed: got %v", result)
+	}
+}
+
+func TestContextSeparators(t *testing.T) {
+	lines := []string{"a", "x", "b", "c", "d", "x", "e", "x", "f"}
+	opts := Options{Pattern: "x", Fixed: true, Before: 1, After: 1, ShowNum: true}
+
+	result, err := Process(lines, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"1-a", "2:x", "3-b", "--", "5-d", "6:x", "7-e", "8:x", "9-f"}
+	if strings.Join(result, "|") != strings.Join(want, "|") {
+		t.Errorf("got %v, want %v", result, want)
+	}
+}
+
+func TestRunStreams(t *testing.T) {
+	input := strings.NewReader("one\r\ntwo\nthree\nfour\nfive")
+	var out strings.Builder
+
+	cnt, err := Run(input, &out, Options{Pattern: "t", Before: 1, After: 0})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cnt != 2 {
+		t.Errorf("count failed: got %d, want 2", cnt)
+	}
+	if want := "one\ntwo\nthree\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestSearchBoundedBefore(t *testing.T) {
+	var lines []string
+	for i := 0; i < 1000; i++ {
+		lines = append(lines, "noise")
+	}
+	lines = append(lines, "hit")
+
+	m, err := Compile(Options{Pattern: "hit", Before: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []Line
+	_, err = search(sliceSource(lines), m.find, m.opts, func(l Line) error {
+		got = append(got, l)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 || got[0].Num != 999 || got[2].Num != 1001 || !got[2].Match || got[0].Match {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestOnlyMatching(t *testing.T) {
+	lines := []string{"foo bar foo", "baz", "xfoo"}
+	opts := Options{Pattern: "fo+", OnlyMatching: true, ShowNum: true, ByteOffset: true}
+
+	result, err := Process(lines, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"1:0:foo", "1:8:foo", "3:17:foo"}
+	if strings.Join(result, "|") != strings.Join(want, "|") {
+		t.Errorf("got %v, want %v", result, want)
+	}
+}
+
+func TestByteOffset(t *testing.T) {
+	var out strings.Builder
+	_, err := Run(strings.NewReader("ab\r\ncd\nab\n"), &out, Options{Pattern: "ab", Fixed: true, ByteOffset: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "0:ab\n7:ab\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestColor(t *testing.T) {
+	lines := []string{"say hi, hi"}
+	opts := Options{Pattern: "hi", Fixed: true, Color: true, ShowNum: true}
+
+	result, err := Process(lines, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hi := "\x1b[01;31m\x1b[Khi\x1b[m\x1b[K"
+	want := "\x1b[32m\x1b[K1\x1b[m\x1b[K\x1b[36m\x1b[K:\x1b[m\x1b[Ksay " + hi + ", " + hi
+	if len(result) != 1 || result[0] != want {
+		t.Errorf("got %q, want %q", result, want)
+	}
+}
+
+func TestMultiplePatterns(t *testing.T) {
+	lines := []string{"error: disk", "warn: cpu", "info: ok", "ERROR: net"}
+
+	for _, fixed := range []bool{false, true} {
+		opts := Options{Patterns: []string{"error", "warn"}, Fixed: fixed}
+		result, err := Process(lines, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(result, "|") != "error: disk|warn: cpu" {
+			t.Errorf("fixed=%v: got %v", fixed, result)
+		}
+
+		opts.IgnoreCase = true
+		result, _ = Process(lines, opts)
+		if len(result) != 3 {
+			t.Errorf("fixed=%v ignore case: got %v", fixed, result)
+		}
+	}
+
+	result, _ := Process(lines, Options{Patterns: []string{}})
+	if len(result) != 0 {
+		t.Errorf("empty pattern list should match nothing: got %v", result)
+	}
+}
+
+func TestWordAndLineMatch(t *testing.T) {
+	lines := []string{"foobar foo", "foo_bar", "foo", "a-foo-b"}
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"word regexp", Options{Pattern: "foo", WordRegexp: true, OnlyMatching: true, ByteOffset: true}, "7:foo|19:foo|25:foo"},
+		{"word fixed", Options{Pattern: "foo", Fixed: true, WordRegexp: true, OnlyMatching: true, ByteOffset: true}, "7:foo|19:foo|25:foo"},
+		{"line regexp", Options{Pattern: "fo+", LineRegexp: true}, "foo"},
+		{"line fixed", Options{Patterns: []string{"foo", "a-foo-b"}, Fixed: true, LineRegexp: true}, "foo|a-foo-b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Process(lines, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Join(result, "|"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAhoCorasickLongestLeftmost(t *testing.T) {
+	ac := newAhoCorasick([]string{"he", "she", "hers", "his"}, true)
+	spans := selectSpans("uSHErs his", ac.find("uSHErs his"), ac.empty, Options{})
+
+	want := [][]int{{1, 4}, {7, 10}}
+	if len(spans) != len(want) {
+		t.Fatalf("got %v, want %v", spans, want)
+	}
+	for i := range want {
+		if spans[i][0] != want[i][0] || spans[i][1] != want[i][1] {
+			t.Errorf("got %v, want %v", spans, want)
+		}
+	}
+}
+
+func TestMaxCount(t *testing.T) {
+	lines := []string{"a1", "b", "a2", "a3", "c", "a4"}
+
+	result, err := Process(lines, Options{Pattern: "a", MaxCount: 2, After: 1, ShowNum: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(result, "|"); got != "1:a1|2-b|3:a2|4-a3" {
+		t.Errorf("got %q", got)
+	}
+
+	result, _ = Process(lines, Options{Pattern: "a", MaxCount: 3, CountOnly: true})
+	if len(result) != 1 || result[0] != "3" {
+		t.Errorf("count with max failed: got %v", result)
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	read := 0
+	next := func() (string, int, bool, error) {
+		read++
+		return "match", 6, true, nil
+	}
+
+	m, err := Compile(Options{Pattern: "match", Quiet: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out []string
+	cnt, err := report(next, false, m.find, m.opts, func(s string) error {
+		out = append(out, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cnt != 1 || read != 1 || len(out) != 0 {
+		t.Errorf("quiet should stop at the first match silently: cnt=%d read=%d out=%v", cnt, read, out)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	_, err := Compile(Options{Patterns: []string{"ok", "bad("}})
+	if err == nil || !strings.HasPrefix(err.Error(), `invalid pattern "bad("`) || strings.Contains(err.Error(), "(?:") {
+		t.Errorf("got error %v", err)
+	}
+
+	m, err := Compile(Options{Patterns: []string{"foo", "bar"}, Fixed: true, WithFileName: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out strings.Builder
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := m.Run(strings.NewReader("foo\nbaz\n"), &out, name); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if want := "a.txt:foo\nb.txt:foo\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,356 @@
+package grep
+
+import (
+	"bufio"
+	"encoding/gob"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	indexVersion  = 1
+	indexFileName = "trigrams.idx"
+)
+
+type Index struct {
+	dir   string
+	files map[string]*indexEntry
+	seen  map[string]bool
+	dirty bool
+}
+
+type indexData struct {
+	Version int
+	Files   map[string]*indexEntry
+}
+
+type indexEntry struct {
+	Size     int64
+	ModTime  int64
+	Trigrams []uint32
+}
+
+func OpenIndex(dir string) (*Index, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	ix := &Index{dir: dir, files: make(map[string]*indexEntry), seen: make(map[string]bool)}
+
+	f, err := os.Open(filepath.Join(dir, indexFileName))
+	if os.IsNotExist(err) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	var data indexData
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&data); err != nil || data.Version != indexVersion {
+		ix.dirty = true
+		return ix, nil
+	}
+	ix.files = data.Files
+	return ix, nil
+}
+
+func (ix *Index) Matcher(opts Options) (func(path string) (bool, error), error) {
+	q, err := indexQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+	return func(path string) (bool, error) {
+		entry, err := ix.update(path)
+		if err != nil {
+			return false, err
+		}
+		return q.eval(entry.Trigrams), nil
+	}, nil
+}
+
+func (ix *Index) update(path string) (*indexEntry, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	ix.seen[abs] = true
+
+	entry := ix.files[abs]
+	if entry != nil && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() {
+		return entry, nil
+	}
+
+	f, err := os.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+	trigrams, err := fileTrigrams(f)
+	if err != nil {
+		return nil, err
+	}
+
+	entry = &indexEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Trigrams: trigrams}
+	ix.files[abs] = entry
+	ix.dirty = true
+	return entry, nil
+}
+
+func (ix *Index) Save(roots []string) error {
+	for _, root := range roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return err
+		}
+		for path := range ix.files {
+			if !ix.seen[path] && (path == abs || strings.HasPrefix(path, abs+string(filepath.Separator))) {
+				delete(ix.files, path)
+				ix.dirty = true
+			}
+		}
+	}
+	if !ix.dirty {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(ix.dir, ".index-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	w := bufio.NewWriter(tmp)
+	if err := gob.NewEncoder(w).Encode(indexData{Version: indexVersion, Files: ix.files}); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(ix.dir, indexFileName)); err != nil {
+		return err
+	}
+	ix.dirty = false
+	return nil
+}
+
+func fileTrigrams(r io.Reader) ([]uint32, error) {
+	set := make(map[uint32]struct{})
+	br, _, err := decodeInput(r)
+	if err != nil {
+		return nil, err
+	}
+	var tri uint32
+	run := 0
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c == '\n' {
+			run = 0
+			continue
+		}
+		tri = (tri<<8 | uint32(foldByte(c))) & 0xFFFFFF
+		if run++; run >= 3 {
+			set[tri] = struct{}{}
+		}
+	}
+
+	trigrams := make([]uint32, 0, len(set))
+	for t := range set {
+		trigrams = append(trigrams, t)
+	}
+	sort.Slice(trigrams, func(i, j int) bool {
+		return trigrams[i] < trigrams[j]
+	})
+	return trigrams, nil
+}
+
+func foldByte(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+type queryOp int
+
+const (
+	queryAll queryOp = iota
+	queryAnd
+	queryOr
+)
+
+type query struct {
+	op       queryOp
+	trigrams []uint32
+	sub      []*query
+}
+
+var matchAll = &query{op: queryAll}
+
+func (q *query) eval(trigrams []uint32) bool {
+	switch q.op {
+	case queryAnd:
+		for _, t := range q.trigrams {
+			i := sort.Search(len(trigrams), func(i int) bool { return trigrams[i] >= t })
+			if i == len(trigrams) || trigrams[i] != t {
+				return false
+			}
+		}
+		for _, sub := range q.sub {
+			if !sub.eval(trigrams) {
+				return false
+			}
+		}
+		return true
+	case queryOr:
+		for _, sub := range q.sub {
+			if sub.eval(trigrams) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func and(a, b *query) *query {
+	switch {
+	case a.op == queryAll:
+		return b
+	case b.op == queryAll:
+		return a
+	}
+	return &query{op: queryAnd, sub: []*query{a, b}}
+}
+
+func or(qs []*query) *query {
+	for _, q := range qs {
+		if q.op == queryAll {
+			return matchAll
+		}
+	}
+	if len(qs) == 1 {
+		return qs[0]
+	}
+	return &query{op: queryOr, sub: qs}
+}
+
+func literalQuery(s string, fold bool) *query {
+	if len(s) < 3 || (fold && !foldsToASCII(s)) {
+		return matchAll
+	}
+	q := &query{op: queryAnd}
+	for i := 0; i+3 <= len(s); i++ {
+		if strings.IndexByte(s[i:i+3], '\n') >= 0 {
+			continue
+		}
+		q.trigrams = append(q.trigrams, uint32(foldByte(s[i]))<<16|uint32(foldByte(s[i+1]))<<8|uint32(foldByte(s[i+2])))
+	}
+	return q
+}
+
+func foldsToASCII(s string) bool {
+	for _, r := range s {
+		for f := r; ; {
+			if f >= utf8.RuneSelf {
+				return false
+			}
+			if f = unicode.SimpleFold(f); f == r {
+				break
+			}
+		}
+	}
+	return true
+}
+
+func indexQuery(opts Options) (*query, error) {
+	if opts.Invert || opts.CountOnly || opts.FilesWithoutMatch || len(opts.Fields) > 0 || opts.Perl {
+		return matchAll, nil
+	}
+
+	var qs []*query
+	for _, p := range opts.patterns() {
+		if opts.Fixed {
+			qs = append(qs, literalQuery(p, opts.IgnoreCase))
+			continue
+		}
+		if opts.Basic {
+			p = translateBasic(p)
+		}
+		flags := syntax.Perl
+		if opts.IgnoreCase {
+			flags |= syntax.FoldCase
+		}
+		re, err := syntax.Parse(p, flags)
+		if err != nil {
+			return nil, err
+		}
+		qs = append(qs, regexpQuery(re.Simplify()))
+	}
+	if len(qs) == 0 {
+		return &query{op: queryOr}, nil
+	}
+	return or(qs), nil
+}
+
+func regexpQuery(re *syntax.Regexp) *query {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return literalQuery(string(re.Rune), re.Flags&syntax.FoldCase != 0)
+	case syntax.OpCapture, syntax.OpPlus:
+		return regexpQuery(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return regexpQuery(re.Sub[0])
+		}
+	case syntax.OpAlternate:
+		qs := make([]*query, len(re.Sub))
+		for i, sub := range re.Sub {
+			qs[i] = regexpQuery(sub)
+		}
+		return or(qs)
+	case syntax.OpConcat:
+		q := matchAll
+		var lit strings.Builder
+		fold := false
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpLiteral {
+				lit.WriteString(string(sub.Rune))
+				fold = fold || sub.Flags&syntax.FoldCase != 0
+				continue
+			}
+			q = and(q, literalQuery(lit.String(), fold))
+			lit.Reset()
+			fold = false
+			q = and(q, regexpQuery(sub))
+		}
+		return and(q, literalQuery(lit.String(), fold))
+	}
+	return matchAll
+}
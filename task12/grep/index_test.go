@@ -0,0 +1,112 @@
+package grep
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndexQuery(t *testing.T) {
+	trigramsOf := func(s string) []uint32 {
+		tris, err := fileTrigrams(strings.NewReader(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tris
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		text string
+		want bool
+	}{
+		{"literal present", Options{Pattern: "needle"}, "a needle here", true},
+		{"literal absent", Options{Pattern: "needle"}, "a noodle here", false},
+		{"case folded", Options{Pattern: "NeEdLe", IgnoreCase: true}, "needle", true},
+		{"non-ASCII fold", Options{Pattern: "kel", IgnoreCase: true}, "\u212Aelvin", true},
+		{"non-ASCII fold fixed", Options{Pattern: "mass", IgnoreCase: true, Fixed: true}, "ma\u017F\u017F", true},
+		{"across lines", Options{Pattern: "abcd"}, "ab\ncd", false},
+		{"alternation", Options{Pattern: "foo|barbaz"}, "xx barbaz", true},
+		{"alternation absent", Options{Pattern: "foo|barbaz"}, "xx bar baz", false},
+		{"optional part", Options{Pattern: "abc(def)?ghi"}, "abcghi", true},
+		{"required plus", Options{Pattern: "x(yzw)+"}, "xyz", false},
+		{"class unconstrained", Options{Pattern: "[a-z]+"}, "q", true},
+		{"fixed list", Options{Patterns: []string{"alpha", "omega"}, Fixed: true}, "omega", true},
+		{"fixed list absent", Options{Patterns: []string{"alpha", "omega"}, Fixed: true}, "beta", false},
+		{"invert not narrowed", Options{Pattern: "needle", Invert: true}, "hay", true},
+		{"no patterns", Options{Patterns: []string{}}, "anything", false},
+		{"basic syntax", Options{Pattern: `ab\(cd\)`, Basic: true}, "abcd", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := indexQuery(tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := q.eval(trigramsOf(tt.text)); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexIncremental(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(t.TempDir(), "idx")
+	a := filepath.Join(root, "a.txt")
+	b := filepath.Join(root, "b.txt")
+	_ = os.WriteFile(a, []byte("hello needle\n"), 0644)
+	_ = os.WriteFile(b, []byte("nothing here\n"), 0644)
+
+	search := func() []string {
+		t.Helper()
+		ix, err := OpenIndex(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		candidate, err := ix.Matcher(Options{Pattern: "needle"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var found []string
+		err = Walk([]string{root}, WalkOptions{Recursive: true}, func(path string, err error) error {
+			if ok, err := candidate(path); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if ok {
+				found = append(found, filepath.Base(path))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := ix.Save([]string{root}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return found
+	}
+
+	if got := strings.Join(search(), ","); got != "a.txt" {
+		t.Errorf("first search: got %s", got)
+	}
+
+	_ = os.WriteFile(b, []byte("now a needle too\n"), 0644)
+	later := time.Now().Add(time.Second)
+	_ = os.Chtimes(b, later, later)
+	if got := strings.Join(search(), ","); got != "a.txt,b.txt" {
+		t.Errorf("after update: got %s", got)
+	}
+
+	_ = os.Remove(a)
+	search()
+	ix, err := OpenIndex(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ix.files) != 1 {
+		t.Errorf("deleted file not pruned: %v", ix.files)
+	}
+}
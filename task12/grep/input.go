@@ -0,0 +1,111 @@
+package grep
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const binaryCheckSize = 32 << 10
+
+func decodeInput(r io.Reader) (*bufio.Reader, bool, error) {
+	br := bufio.NewReaderSize(r, binaryCheckSize)
+	bom, err := peekBuffered(br)
+	if err != nil {
+		return nil, false, err
+	}
+	switch {
+	case bytes.HasPrefix(bom, []byte{0xEF, 0xBB, 0xBF}):
+		_, _ = br.Discard(3)
+	case bytes.HasPrefix(bom, []byte{0xFF, 0xFE}), bytes.HasPrefix(bom, []byte{0xFE, 0xFF}):
+		_, _ = br.Discard(2)
+		br = bufio.NewReaderSize(&utf16Reader{r: br, bigEndian: bom[0] == 0xFE}, binaryCheckSize)
+	}
+
+	head, err := peekBuffered(br)
+	if err != nil {
+		return nil, false, err
+	}
+	return br, bytes.IndexByte(head, 0) >= 0, nil
+}
+
+func peekBuffered(br *bufio.Reader) ([]byte, error) {
+	if _, err := br.Peek(1); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return br.Peek(br.Buffered())
+}
+
+type utf16Reader struct {
+	r         *bufio.Reader
+	bigEndian bool
+	unread    []uint16
+	pending   []byte
+}
+
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(u.pending) == 0 {
+			r, err := u.readRune()
+			if err != nil {
+				if n > 0 && err == io.EOF {
+					return n, nil
+				}
+				return n, err
+			}
+			var buf [utf8.UTFMax]byte
+			u.pending = buf[:utf8.EncodeRune(buf[:], r)]
+		}
+		c := copy(p[n:], u.pending)
+		u.pending = u.pending[c:]
+		n += c
+		if u.r.Buffered() == 0 && len(u.unread) == 0 && len(u.pending) == 0 {
+			break
+		}
+	}
+	return n, nil
+}
+
+func (u *utf16Reader) readRune() (rune, error) {
+	first, err := u.readUnit()
+	if err != nil {
+		return 0, err
+	}
+	if !utf16.IsSurrogate(rune(first)) {
+		return rune(first), nil
+	}
+	second, err := u.readUnit()
+	if err == io.EOF {
+		return utf8.RuneError, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	r := utf16.DecodeRune(rune(first), rune(second))
+	if r == utf8.RuneError {
+		u.unread = append(u.unread, second)
+	}
+	return r, nil
+}
+
+func (u *utf16Reader) readUnit() (uint16, error) {
+	if len(u.unread) > 0 {
+		unit := u.unread[0]
+		u.unread = u.unread[1:]
+		return unit, nil
+	}
+	var b [2]byte
+	if _, err := io.ReadFull(u.r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return utf8.RuneError, nil
+		}
+		return 0, err
+	}
+	if u.bigEndian {
+		return uint16(b[0])<<8 | uint16(b[1]), nil
+	}
+	return uint16(b[1])<<8 | uint16(b[0]), nil
+}
@@ -0,0 +1,104 @@
+package grep
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBinaryFiles(t *testing.T) {
+	data := "text\x00more\nfoo here\n"
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"binary", Options{Pattern: "foo", FileName: "a.bin"}, "Binary file a.bin matches\n"},
+		{"binary no match", Options{Pattern: "zzz", FileName: "a.bin"}, ""},
+		{"text", Options{Pattern: "foo", FileName: "a.bin", BinaryFiles: "text"}, "foo here\n"},
+		{"without match", Options{Pattern: "foo", FileName: "a.bin", BinaryFiles: "without-match"}, ""},
+		{"without match lists", Options{Pattern: "foo", FileName: "a.bin", BinaryFiles: "without-match", FilesWithoutMatch: true}, "a.bin\n"},
+		{"count", Options{Pattern: "o", FileName: "a.bin", CountOnly: true}, "2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			if _, err := Run(strings.NewReader(data), &out, tt.opts); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLateBinary(t *testing.T) {
+	data := "foo first\nx\x00foo\nfoo last\n"
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"binary", Options{Pattern: "foo", FileName: "a.bin"}, "foo first\nBinary file a.bin matches\n"},
+		{"binary no later match", Options{Pattern: "first", FileName: "a.bin"}, "foo first\n"},
+		{"text", Options{Pattern: "foo", FileName: "a.bin", BinaryFiles: "text"}, "foo first\nx\x00foo\nfoo last\n"},
+		{"without match", Options{Pattern: "foo", FileName: "a.bin", BinaryFiles: "without-match"}, "foo first\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			r := io.MultiReader(strings.NewReader(data[:10]), strings.NewReader(data[10:]))
+			if _, err := Run(r, &out, tt.opts); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestUTF16(t *testing.T) {
+	le := "\xff\xfeh\x00i\x00\n\x00\x3d\xd8\x00\xde\n\x00"
+	be := "\xfe\xff\x00h\x00i\x00\n\xd8\x3d\xde\x00\x00\n"
+
+	for name, data := range map[string]string{"le": le, "be": be} {
+		lines, err := ReadLines(strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if len(lines) != 2 || lines[0] != "hi" || lines[1] != "\U0001F600" {
+			t.Errorf("%s: got %q", name, lines)
+		}
+
+		var out strings.Builder
+		if _, err := Run(strings.NewReader(data), &out, Options{Pattern: "hi"}); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if out.String() != "hi\n" {
+			t.Errorf("%s: got %q", name, out.String())
+		}
+	}
+}
+
+func TestLongLines(t *testing.T) {
+	long := strings.Repeat("x", 1<<20) + "needle"
+	lines, err := ReadLines(strings.NewReader("short\n" + long + "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lines) != 2 || lines[1] != long {
+		t.Fatalf("long line not read: got %d lines", len(lines))
+	}
+
+	var out strings.Builder
+	if _, err := Run(strings.NewReader(long), &out, Options{Pattern: "needle", Fixed: true, OnlyMatching: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "needle\n" {
+		t.Errorf("got %q", out.String())
+	}
+}
@@ -0,0 +1,150 @@
+package grep
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+type Summary struct {
+	Elapsed           time.Duration
+	Searches          int
+	SearchesWithMatch int
+	MatchedLines      int
+}
+
+type jsonEvent struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+type jsonText struct {
+	Text string `json:"text"`
+}
+
+type jsonLine struct {
+	Path           jsonText       `json:"path"`
+	Lines          jsonText       `json:"lines"`
+	LineNumber     int            `json:"line_number"`
+	AbsoluteOffset int64          `json:"absolute_offset"`
+	Submatches     []jsonSubmatch `json:"submatches"`
+}
+
+type jsonSubmatch struct {
+	Match jsonText `json:"match"`
+	Start int      `json:"start"`
+	End   int      `json:"end"`
+}
+
+type jsonBegin struct {
+	Path jsonText `json:"path"`
+}
+
+type jsonEnd struct {
+	Path  jsonText  `json:"path"`
+	Stats jsonStats `json:"stats"`
+}
+
+type jsonSummary struct {
+	ElapsedTotal jsonElapsed      `json:"elapsed_total"`
+	Stats        jsonSummaryStats `json:"stats"`
+}
+
+type jsonSummaryStats struct {
+	Searches          int `json:"searches"`
+	SearchesWithMatch int `json:"searches_with_match"`
+	MatchedLines      int `json:"matched_lines"`
+}
+
+type jsonStats struct {
+	Elapsed           jsonElapsed `json:"elapsed"`
+	Searches          int         `json:"searches"`
+	SearchesWithMatch int         `json:"searches_with_match"`
+	MatchedLines      int         `json:"matched_lines"`
+	Matches           int         `json:"matches"`
+}
+
+type jsonElapsed struct {
+	Secs  int64  `json:"secs"`
+	Nanos int    `json:"nanos"`
+	Human string `json:"human"`
+}
+
+func newJSONElapsed(d time.Duration) jsonElapsed {
+	return jsonElapsed{
+		Secs:  int64(d / time.Second),
+		Nanos: int(d % time.Second),
+		Human: fmt.Sprintf("%.6fs", d.Seconds()),
+	}
+}
+
+func reportJSON(next lineSource, find matcher, opts Options, out func(string) error) (int, error) {
+	start := time.Now()
+	path := jsonText{Text: opts.FileName}
+	emit := func(typ string, data interface{}) error {
+		b, err := json.Marshal(jsonEvent{Type: typ, Data: data})
+		if err != nil {
+			return err
+		}
+		return out(string(b))
+	}
+
+	if err := emit("begin", jsonBegin{Path: path}); err != nil {
+		return 0, err
+	}
+	matches := 0
+	cnt, err := search(next, find, opts, func(line Line) error {
+		typ := "context"
+		if line.Match {
+			typ = "match"
+		}
+		submatches := []jsonSubmatch{}
+		for _, m := range line.Matches {
+			if m[0] == m[1] {
+				continue
+			}
+			submatches = append(submatches, jsonSubmatch{Match: jsonText{Text: line.Text[m[0]:m[1]]}, Start: m[0], End: m[1]})
+		}
+		if line.Match {
+			matches += len(submatches)
+		}
+		return emit(typ, jsonLine{
+			Path:           path,
+			Lines:          jsonText{Text: line.Text},
+			LineNumber:     line.Num,
+			AbsoluteOffset: line.Offset,
+			Submatches:     submatches,
+		})
+	})
+	if err != nil {
+		return cnt, err
+	}
+
+	stats := jsonStats{
+		Elapsed:      newJSONElapsed(time.Since(start)),
+		Searches:     1,
+		MatchedLines: cnt,
+		Matches:      matches,
+	}
+	if cnt > 0 {
+		stats.SearchesWithMatch = 1
+	}
+	return cnt, emit("end", jsonEnd{Path: path, Stats: stats})
+}
+
+func WriteJSONSummary(w io.Writer, s Summary) error {
+	b, err := json.Marshal(jsonEvent{Type: "summary", Data: jsonSummary{
+		ElapsedTotal: newJSONElapsed(s.Elapsed),
+		Stats: jsonSummaryStats{
+			Searches:          s.Searches,
+			SearchesWithMatch: s.SearchesWithMatch,
+			MatchedLines:      s.MatchedLines,
+		},
+	}})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
@@ -0,0 +1,72 @@
+package grep
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONEvents(t *testing.T) {
+	lines := []string{"foo bar", "baz", "foo foo"}
+	result, err := Process(lines, Options{Pattern: "foo", FileName: "a.txt", After: 1, JSON: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var types []string
+	for _, line := range result {
+		var ev struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		types = append(types, ev.Type)
+	}
+	if got := strings.Join(types, ","); got != "begin,match,context,match,end" {
+		t.Fatalf("got events %s", got)
+	}
+
+	var match jsonLine
+	if err := json.Unmarshal([]byte(result[3]), &jsonEvent{Data: &match}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if match.Path.Text != "a.txt" || match.LineNumber != 3 || match.AbsoluteOffset != 12 || len(match.Submatches) != 2 {
+		t.Errorf("got %+v", match)
+	}
+	if sm := match.Submatches[1]; sm.Match.Text != "foo" || sm.Start != 4 || sm.End != 7 {
+		t.Errorf("got submatch %+v", sm)
+	}
+
+	var end jsonEnd
+	if err := json.Unmarshal([]byte(result[4]), &jsonEvent{Data: &end}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if end.Stats.MatchedLines != 2 || end.Stats.Matches != 3 || end.Stats.SearchesWithMatch != 1 {
+		t.Errorf("got stats %+v", end.Stats)
+	}
+}
+
+func TestJSONBinary(t *testing.T) {
+	var out strings.Builder
+	if _, err := Run(strings.NewReader("a\x00\nfoo\n"), &out, Options{Pattern: "foo", FileName: "c.bin", JSON: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), `"type":"match"`) {
+		t.Errorf("binary file reported matches: %s", out.String())
+	}
+}
+
+func TestJSONSummary(t *testing.T) {
+	var out strings.Builder
+	err := WriteJSONSummary(&out, Summary{Elapsed: 1500 * time.Millisecond, Searches: 3, SearchesWithMatch: 2, MatchedLines: 7})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"type":"summary","data":{"elapsed_total":{"secs":1,"nanos":500000000,"human":"1.500000s"},"stats":{"searches":3,"searches_with_match":2,"matched_lines":7}}}` + "\n"
+	if out.String() != want {
+		t.Errorf("got %s", out.String())
+	}
+}
@@ -0,0 +1,144 @@
+package grep
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func (o Options) patterns() []string {
+	if o.Patterns != nil {
+		return o.Patterns
+	}
+	return []string{o.Pattern}
+}
+
+type matcher func(string) ([][]int, error)
+
+func matchFunc(opts Options) (matcher, error) {
+	find, err := patternMatchFunc(opts)
+	if err != nil || len(opts.Fields) == 0 {
+		return find, err
+	}
+	return fieldMatchFunc(opts, find)
+}
+
+func patternMatchFunc(opts Options) (matcher, error) {
+	patterns := opts.patterns()
+	if len(patterns) == 0 {
+		return func(string) ([][]int, error) {
+			return nil, nil
+		}, nil
+	}
+
+	if opts.Fixed && (!opts.IgnoreCase || isASCII(patterns)) {
+		ac := newAhoCorasick(patterns, opts.IgnoreCase)
+		return func(s string) ([][]int, error) {
+			return selectSpans(s, ac.find(s), ac.empty, opts), nil
+		}, nil
+	}
+	if opts.Perl && !opts.Fixed {
+		return perlMatchFunc(patterns, opts)
+	}
+
+	alts := make([]string, len(patterns))
+	for i, p := range patterns {
+		switch {
+		case opts.Fixed:
+			p = regexp.QuoteMeta(p)
+		case opts.Basic:
+			p = translateBasic(p)
+		}
+		if _, err := syntax.Parse(p, syntax.Perl); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", patterns[i], err)
+		}
+		alts[i] = "(?:" + p + ")"
+	}
+	expr := strings.Join(alts, "|")
+	switch {
+	case opts.LineRegexp:
+		expr = "^(?:" + expr + ")$"
+	case opts.WordRegexp:
+		expr = `(?:^|[^\pL\pN_])(` + expr + ")"
+	}
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	re.Longest()
+
+	if opts.WordRegexp && !opts.LineRegexp {
+		return func(s string) ([][]int, error) {
+			var spans [][]int
+			for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+				if m[2] >= 0 && !isWordAt(s, m[3]) {
+					spans = append(spans, []int{m[2], m[3]})
+				}
+			}
+			return spans, nil
+		}, nil
+	}
+	return func(s string) ([][]int, error) {
+		return re.FindAllStringIndex(s, -1), nil
+	}, nil
+}
+
+func selectSpans(s string, candidates [][]int, empty bool, opts Options) [][]int {
+	longest := make(map[int]int)
+	for _, c := range candidates {
+		switch {
+		case opts.LineRegexp && (c[0] != 0 || c[1] != len(s)):
+			continue
+		case opts.WordRegexp && !opts.LineRegexp && (isWordBefore(s, c[0]) || isWordAt(s, c[1])):
+			continue
+		}
+		if end, ok := longest[c[0]]; !ok || c[1] > end {
+			longest[c[0]] = c[1]
+		}
+	}
+
+	var spans [][]int
+	for start := 0; start <= len(s) && len(longest) > 0; start++ {
+		if end, ok := longest[start]; ok {
+			spans = append(spans, []int{start, end})
+			if end > start {
+				start = end - 1
+			}
+		}
+	}
+	if spans == nil && empty && (!opts.LineRegexp || s == "") {
+		return [][]int{{0, 0}}
+	}
+	return spans
+}
+
+func isWordAt(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return i < len(s) && isWordRune(r)
+}
+
+func isWordBefore(s string, i int) bool {
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return i > 0 && isWordRune(r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func isASCII(patterns []string) bool {
+	for _, p := range patterns {
+		for i := 0; i < len(p); i++ {
+			if p[i] >= utf8.RuneSelf {
+				return false
+			}
+		}
+	}
+	return true
+}
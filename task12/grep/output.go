@@ -0,0 +1,101 @@
+package grep
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	colorMatch     = "01;31"
+	colorFileName  = "35"
+	colorLineNum   = "32"
+	colorOffset    = "32"
+	colorSeparator = "36"
+)
+
+type printer struct {
+	opts Options
+	out  func(string) error
+	last int
+}
+
+func (p *printer) print(line Line) error {
+	if p.opts.OnlyMatching {
+		return p.printMatches(line)
+	}
+	if p.last > 0 && line.Num > p.last+1 && (p.opts.Before > 0 || p.opts.After > 0) {
+		if err := p.out(p.paint(colorSeparator, "--")); err != nil {
+			return err
+		}
+	}
+	p.last = line.Num
+
+	sep := byte('-')
+	if line.Match {
+		sep = ':'
+	}
+	return p.out(p.prefix(sep) + p.position(line.Num, line.Offset, sep) + p.highlight(line))
+}
+
+func (p *printer) printMatches(line Line) error {
+	if !line.Match {
+		return nil
+	}
+	for _, m := range line.Matches {
+		if m[0] == m[1] {
+			continue
+		}
+		text := p.paint(colorMatch, line.Text[m[0]:m[1]])
+		if err := p.out(p.prefix(':') + p.position(line.Num, line.Offset+int64(m[0]), ':') + text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *printer) prefix(sep byte) string {
+	if !p.opts.WithFileName {
+		return ""
+	}
+	return p.paint(colorFileName, p.opts.FileName) + p.separator(sep)
+}
+
+func (p *printer) position(num int, offset int64, sep byte) string {
+	var b strings.Builder
+	if p.opts.ShowNum {
+		b.WriteString(p.paint(colorLineNum, strconv.Itoa(num)) + p.separator(sep))
+	}
+	if p.opts.ByteOffset {
+		b.WriteString(p.paint(colorOffset, strconv.FormatInt(offset, 10)) + p.separator(sep))
+	}
+	return b.String()
+}
+
+func (p *printer) highlight(line Line) string {
+	if !p.opts.Color || len(line.Matches) == 0 {
+		return line.Text
+	}
+	var b strings.Builder
+	prev := 0
+	for _, m := range line.Matches {
+		if m[0] == m[1] {
+			continue
+		}
+		b.WriteString(line.Text[prev:m[0]])
+		b.WriteString(p.paint(colorMatch, line.Text[m[0]:m[1]]))
+		prev = m[1]
+	}
+	b.WriteString(line.Text[prev:])
+	return b.String()
+}
+
+func (p *printer) separator(sep byte) string {
+	return p.paint(colorSeparator, string(sep))
+}
+
+func (p *printer) paint(color, s string) string {
+	if !p.opts.Color {
+		return s
+	}
+	return "\x1b[" + color + "m\x1b[K" + s + "\x1b[m\x1b[K"
+}
@@ -0,0 +1,70 @@
+package grep
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dlclark/regexp2"
+)
+
+const defaultMatchTimeout = time.Second
+
+func perlMatchFunc(patterns []string, opts Options) (matcher, error) {
+	var flags regexp2.RegexOptions
+	if opts.IgnoreCase {
+		flags |= regexp2.IgnoreCase
+	}
+	timeout := opts.MatchTimeout
+	if timeout <= 0 {
+		timeout = defaultMatchTimeout
+	}
+
+	res := make([]*regexp2.Regexp, len(patterns))
+	for i, p := range patterns {
+		if _, err := regexp2.Compile(p, flags); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", patterns[i], err)
+		}
+		switch {
+		case opts.LineRegexp:
+			p = "^(?:" + p + ")$"
+		case opts.WordRegexp:
+			p = `(?<![\p{L}\p{N}_])(?:` + p + `)(?![\p{L}\p{N}_])`
+		}
+		re, err := regexp2.Compile(p, flags)
+		if err != nil {
+			return nil, err
+		}
+		re.MatchTimeout = timeout
+		res[i] = re
+	}
+
+	return func(s string) ([][]int, error) {
+		offsets := runeOffsets(s)
+		var spans [][]int
+		for _, re := range res {
+			m, err := re.FindStringMatch(s)
+			for m != nil && err == nil {
+				spans = append(spans, []int{offsets[m.Index], offsets[m.Index+m.Length]})
+				m, err = re.FindNextMatch(m)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(res) > 1 {
+			spans = selectSpans(s, spans, false, Options{})
+		}
+		return spans, nil
+	}, nil
+}
+
+func runeOffsets(s string) []int {
+	offsets := make([]int, 0, len(s)+1)
+	for i := 0; i < len(s); {
+		offsets = append(offsets, i)
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return append(offsets, len(s))
+}
@@ -0,0 +1,104 @@
+package grep
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+type Job struct {
+	Path string
+	Err  error
+}
+
+type FileResult struct {
+	Path  string
+	Count int
+	Err   error
+}
+
+type orderedWriter struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	direct io.Writer
+	err    error
+}
+
+func (o *orderedWriter) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.err != nil {
+		return 0, o.err
+	}
+	if o.direct != nil {
+		n, err := o.direct.Write(p)
+		o.err = err
+		return n, err
+	}
+	return o.buf.Write(p)
+}
+
+func (o *orderedWriter) promote(w io.Writer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, err := w.Write(o.buf.Bytes()); err != nil {
+		o.err = err
+	}
+	o.buf = bytes.Buffer{}
+	o.direct = w
+}
+
+func SearchAll(ctx context.Context, jobs <-chan Job, workers int, w io.Writer, search func(path string, w io.Writer) (int, error)) <-chan FileResult {
+	if workers < 1 {
+		workers = 1
+	}
+
+	type task struct {
+		job    Job
+		out    *orderedWriter
+		result chan FileResult
+	}
+	tasks := make(chan task)
+	order := make(chan task, workers)
+	results := make(chan FileResult)
+
+	go func() {
+		defer close(tasks)
+		defer close(order)
+		for job := range jobs {
+			t := task{job: job, out: &orderedWriter{}, result: make(chan FileResult, 1)}
+			select {
+			case order <- t:
+			case <-ctx.Done():
+				return
+			}
+			tasks <- t
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for t := range tasks {
+				res := FileResult{Path: t.job.Path, Err: t.job.Err}
+				switch {
+				case res.Err != nil:
+				case ctx.Err() != nil:
+					res.Err = ctx.Err()
+				default:
+					res.Count, res.Err = search(t.job.Path, t.out)
+				}
+				t.result <- res
+			}
+		}()
+	}
+
+	go func() {
+		defer close(results)
+		for t := range order {
+			t.out.promote(w)
+			results <- <-t.result
+		}
+	}()
+	return results
+}
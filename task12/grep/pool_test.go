@@ -0,0 +1,93 @@
+package grep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchAllKeepsOrder(t *testing.T) {
+	jobs := make(chan Job)
+	go func() {
+		defer close(jobs)
+		for i := 0; i < 20; i++ {
+			job := Job{Path: fmt.Sprint(i)}
+			if i == 7 {
+				job.Err = errors.New("broken")
+			}
+			jobs <- job
+		}
+	}()
+
+	search := func(path string, w io.Writer) (int, error) {
+		var n int
+		_, _ = fmt.Sscan(path, &n)
+		time.Sleep(time.Duration(20-n) * time.Millisecond)
+		_, err := fmt.Fprintln(w, path)
+		return n, err
+	}
+
+	var out syncBuilder
+	var want strings.Builder
+	i := 0
+	for res := range SearchAll(context.Background(), jobs, 4, &out, search) {
+		if res.Path != fmt.Sprint(i) {
+			t.Fatalf("result %d: got path %s", i, res.Path)
+		}
+		if i == 7 {
+			if res.Err == nil {
+				t.Errorf("job error not passed through: %+v", res)
+			}
+		} else {
+			if res.Count != i {
+				t.Errorf("result %d: got %+v", i, res)
+			}
+			want.WriteString(fmt.Sprintln(i))
+		}
+		i++
+	}
+	if i != 20 {
+		t.Errorf("got %d results, want 20", i)
+	}
+	if out.String() != want.String() {
+		t.Errorf("got output %q, want %q", out.String(), want.String())
+	}
+}
+
+func TestSearchAllStreamsHead(t *testing.T) {
+	jobs := make(chan Job, 2)
+	jobs <- Job{Path: "head"}
+	jobs <- Job{Path: "next"}
+	close(jobs)
+
+	release := make(chan struct{})
+	search := func(path string, w io.Writer) (int, error) {
+		_, _ = fmt.Fprintln(w, path+" first")
+		if path == "head" {
+			<-release
+		}
+		_, err := fmt.Fprintln(w, path+" last")
+		return 1, err
+	}
+
+	var out syncBuilder
+	results := SearchAll(context.Background(), jobs, 2, &out, search)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for out.String() != "head first\n" {
+		if time.Now().After(deadline) {
+			t.Fatalf("head output not streamed: got %q", out.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+	for range results {
+	}
+	if want := "head first\nhead last\nnext first\nnext last\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,36 @@
+package grep
+
+type ring struct {
+	lines []Line
+	start int
+	size  int
+}
+
+func newRing(capacity int) *ring {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &ring{lines: make([]Line, capacity)}
+}
+
+func (r *ring) push(line Line) {
+	if len(r.lines) == 0 {
+		return
+	}
+	if r.size < len(r.lines) {
+		r.lines[(r.start+r.size)%len(r.lines)] = line
+		r.size++
+		return
+	}
+	r.lines[r.start] = line
+	r.start = (r.start + 1) % len(r.lines)
+}
+
+func (r *ring) drain() []Line {
+	out := make([]Line, 0, r.size)
+	for i := 0; i < r.size; i++ {
+		out = append(out, r.lines[(r.start+i)%len(r.lines)])
+	}
+	r.start, r.size = 0, 0
+	return out
+}
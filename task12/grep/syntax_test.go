@@ -0,0 +1,95 @@
+package grep
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTranslateBasic(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`a\(b\)*`, `a(b)*`},
+		{`a(b)+?`, `a\(b\)\+\?`},
+		{`x\{2,3\}`, `x{2,3}`},
+		{`a{2}|b`, `a\{2\}\|b`},
+		{`a\|b`, `a|b`},
+		{`*a`, `\*a`},
+		{`^*a`, `^\*a`},
+		{`\(*a\)`, `(\*a)`},
+		{`a^b$c$`, `a\^b\$c$`},
+		{`\(a$\)`, `(a$)`},
+		{`[]a\]x`, `[]a\\]x`},
+		{`[[:digit:]]+`, `[[:digit:]]\+`},
+		{`a\.b`, `a\.b`},
+	}
+	for _, tt := range tests {
+		if got := translateBasic(tt.in); got != tt.want {
+			t.Errorf("translateBasic(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBasicSyntax(t *testing.T) {
+	lines := []string{"a+b", "aab", "(x)", "x"}
+
+	result, err := Process(lines, Options{Pattern: `a+b`, Basic: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(result, "|") != "a+b" {
+		t.Errorf("got %v", result)
+	}
+
+	result, _ = Process(lines, Options{Pattern: `a\+b`, Basic: true})
+	if strings.Join(result, "|") != "aab" {
+		t.Errorf("got %v", result)
+	}
+
+	result, _ = Process(lines, Options{Pattern: `^(x)$`, Basic: true})
+	if strings.Join(result, "|") != "(x)" {
+		t.Errorf("got %v", result)
+	}
+}
+
+func TestPerl(t *testing.T) {
+	lines := []string{"foo foo", "foo bar", "price: $100", "cost 100", "Ünïcode Ünïcode"}
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"backreference", Options{Pattern: `(\w+) \1`, Perl: true}, "foo foo|Ünïcode Ünïcode"},
+		{"lookahead", Options{Pattern: `foo(?= bar)`, Perl: true, OnlyMatching: true, ByteOffset: true}, "8:foo"},
+		{"lookbehind", Options{Pattern: `(?<=\$)\d+`, Perl: true, OnlyMatching: true}, "100"},
+		{"unicode offsets", Options{Pattern: `(\S+) \1`, Perl: true, OnlyMatching: true, ByteOffset: true}, "0:foo foo|37:Ünïcode Ünïcode"},
+		{"word", Options{Pattern: `\d+`, Perl: true, WordRegexp: true, OnlyMatching: true}, "100|100"},
+		{"multiple", Options{Patterns: []string{`bar`, `fo+`}, Perl: true, OnlyMatching: true, ShowNum: true}, "1:foo|1:foo|2:foo|2:bar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Process(lines, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Join(result, "|"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerlTimeout(t *testing.T) {
+	lines := []string{strings.Repeat("a", 40) + "!"}
+	opts := Options{Pattern: `^(a+)+$`, Perl: true, MatchTimeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	if _, err := Process(lines, opts); err == nil {
+		t.Fatal("expected a match timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("timeout not enforced: took %v", elapsed)
+	}
+}
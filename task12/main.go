@@ -1,79 +1,400 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
 	"task12/grep"
 )
 
+const followInterval = 250 * time.Millisecond
+
+const (
+	exitMatch   = 0
+	exitNoMatch = 1
+	exitError   = 2
+)
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func main() {
 	after := flag.Int("A", 0, "Print N lines after match")
 	before := flag.Int("B", 0, "Print N lines before match")
-	context := flag.Int("C", 0, "Print N lines of context around match")
+	contextLines := flag.Int("C", 0, "Print N lines of context around match")
 	countOnly := flag.Bool("c", false, "Print only count of matching lines")
 	ignoreCase := flag.Bool("i", false, "Ignore case distinctions")
 	invert := flag.Bool("v", false, "Invert match")
 	fixed := flag.Bool("F", false, "Fixed string match")
 	showNum := flag.Bool("n", false, "Show line numbers")
+	recursive := flag.Bool("r", false, "Search directories recursively, skipping symlinks")
+	dereference := flag.Bool("R", false, "Search directories recursively, following symlinks")
+	withFileName := flag.Bool("H", false, "Print the file name for each match")
+	noFileName := flag.Bool("h", false, "Suppress the file name prefix on output")
+	filesWithMatches := flag.Bool("l", false, "Print only names of files with matches")
+	filesWithoutMatch := flag.Bool("L", false, "Print only names of files without matches")
+	onlyMatching := flag.Bool("o", false, "Print only the matched parts of matching lines, each on its own line")
+	byteOffset := flag.Bool("b", false, "Print the byte offset of each output line (or match with -o)")
+	color := flag.String("color", "never", "Highlight matches: auto, always or never")
+	wordRegexp := flag.Bool("w", false, "Match only whole words")
+	lineRegexp := flag.Bool("x", false, "Match only whole lines")
+	quiet := flag.Bool("q", false, "Quiet: print nothing, exit 0 on the first match")
+	maxCount := flag.Int("m", 0, "Stop reading a file after NUM matching lines (0 means no limit)")
+	workers := flag.Int("j", runtime.NumCPU(), "Number of files searched in parallel")
+	jsonOut := flag.Bool("json", false, "Print results as JSON events, one object per line")
+	text := flag.Bool("a", false, "Process binary files as if they were text")
+	binaryFiles := flag.String("binary-files", "binary", "How to treat binary files: binary, text or without-match")
+	extended := flag.Bool("E", false, "Interpret patterns as extended regular expressions (default)")
+	basic := flag.Bool("G", false, "Interpret patterns as POSIX basic regular expressions")
+	perl := flag.Bool("P", false, "Interpret patterns as Perl-compatible regular expressions (lookaround, backreferences)")
+	matchTimeout := flag.Duration("match-timeout", time.Second, "Time budget for a single -P match")
+	indexDir := flag.String("index-dir", "", "Keep a trigram index of searched files in DIR and only read files that can match")
+	follow := flag.Bool("follow", false, "Keep reading FILE as it grows, reopening it after rotation or truncation")
+	var decompress bool
+	flag.BoolVar(&decompress, "z", false, "Decompress gzip, bzip2 and zstd input (detected by magic bytes)")
+	flag.BoolVar(&decompress, "decompress", false, "Same as -z")
+	var patterns, patternFiles stringList
+	flag.Var(&patterns, "e", "Use PATTERN for matching, may be repeated")
+	flag.Var(&patternFiles, "f", "Read patterns from FILE, one per line, may be repeated")
+	var fieldSpecs stringList
+	flag.Var(&fieldSpecs, "field", "Match JSON or logfmt field PATH (PATH=VALUE, PATH~PATTERN or PATH against -e patterns), may be repeated")
+	unparsed := flag.String("unparsed", "skip", "With --field, what to do with lines that are neither JSON nor logfmt: skip or pass")
+	var include, exclude, excludeDir stringList
+	flag.Var(&include, "include", "Search only files whose base name matches GLOB")
+	flag.Var(&exclude, "exclude", "Skip files whose base name matches GLOB")
+	flag.Var(&excludeDir, "exclude-dir", "Skip directories whose base name matches GLOB")
 
 	flag.Parse()
 
-	if flag.NArg() < 1 {
-		os.Exit(1)
+	if strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe") == "zgrep" {
+		decompress = true
 	}
 
-	pattern := flag.Arg(0)
-	var filename string
-	if flag.NArg() > 1 {
-		filename = flag.Arg(1)
+	var fields []grep.FieldFilter
+	for _, spec := range fieldSpecs {
+		f, err := grep.ParseFieldFilter(spec)
+		if err != nil {
+			fatal(err)
+		}
+		fields = append(fields, f)
 	}
-
-	if *context > 0 {
-		*after, *before = *context, *context
+	if *unparsed != "skip" && *unparsed != "pass" {
+		fatal(fmt.Errorf("invalid unparsed mode: %q", *unparsed))
 	}
 
-	var input *os.File
-	var err error
-	if filename != "" {
-		input, err = os.Open(filename)
+	if (len(patternFiles) > 0 || len(fields) > 0) && patterns == nil {
+		patterns = stringList{}
+	}
+	for _, fileName := range patternFiles {
+		lines, err := readPatterns(fileName)
 		if err != nil {
-			log.Fatal(err)
+			fatal(err)
 		}
-		defer func(input *os.File) {
-			err := input.Close()
-			if err != nil {
-				log.Fatal(err)
-			}
-		}(input)
-	} else {
-		input = os.Stdin
+		patterns = append(patterns, lines...)
+	}
+
+	paths := flag.Args()
+	if patterns == nil {
+		if flag.NArg() < 1 {
+			flag.Usage()
+			os.Exit(exitError)
+		}
+		patterns = stringList{flag.Arg(0)}
+		paths = paths[1:]
+	}
+
+	engines := 0
+	for _, set := range []bool{*extended, *basic, *perl, *fixed} {
+		if set {
+			engines++
+		}
+	}
+	if engines > 1 {
+		fatal(errors.New("conflicting matchers specified"))
+	}
+
+	if *text {
+		*binaryFiles = "text"
+	}
+	switch *binaryFiles {
+	case "binary", "text", "without-match":
+	default:
+		fatal(fmt.Errorf("invalid binary-files type: %q", *binaryFiles))
+	}
+
+	if *jsonOut && (*countOnly || *filesWithMatches || *filesWithoutMatch) {
+		fatal(errors.New("--json cannot be combined with -c, -l or -L"))
 	}
 
-	lines, err := grep.ReadLines(input)
+	useColor, err := colorEnabled(*color)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
+	}
+
+	if *contextLines > 0 {
+		*after, *before = *contextLines, *contextLines
+	}
+
+	walkOpts := grep.WalkOptions{
+		Recursive:   *recursive || *dereference,
+		FollowLinks: *dereference,
+		Include:     include,
+		Exclude:     exclude,
+		ExcludeDir:  excludeDir,
+	}
+	implicitRoot := false
+	if len(paths) == 0 {
+		if walkOpts.Recursive {
+			paths, implicitRoot = []string{"."}, true
+		} else {
+			paths = []string{"-"}
+		}
+	}
+
+	if *follow {
+		if len(paths) != 1 || paths[0] == "-" || walkOpts.Recursive || decompress {
+			fatal(errors.New("--follow needs exactly one file and cannot be combined with -r or -z"))
+		}
+		*workers = 1
 	}
 
 	opts := grep.Options{
-		After:      *after,
-		Before:     *before,
-		CountOnly:  *countOnly,
-		IgnoreCase: *ignoreCase,
-		Invert:     *invert,
-		Fixed:      *fixed,
-		ShowNum:    *showNum,
-		Pattern:    pattern,
+		After:             *after,
+		Before:            *before,
+		CountOnly:         *countOnly,
+		IgnoreCase:        *ignoreCase,
+		Invert:            *invert,
+		Fixed:             *fixed,
+		ShowNum:           *showNum,
+		Patterns:          patterns,
+		WordRegexp:        *wordRegexp,
+		LineRegexp:        *lineRegexp,
+		WithFileName:      (len(paths) > 1 || walkOpts.Recursive) && !*noFileName,
+		FilesWithMatches:  *filesWithMatches,
+		FilesWithoutMatch: *filesWithoutMatch,
+		OnlyMatching:      *onlyMatching,
+		ByteOffset:        *byteOffset,
+		Color:             useColor,
+		Quiet:             *quiet,
+		MaxCount:          *maxCount,
+		JSON:              *jsonOut,
+		BinaryFiles:       *binaryFiles,
+		Perl:              *perl,
+		Basic:             *basic,
+		MatchTimeout:      *matchTimeout,
+		Fields:            fields,
+		Unparsed:          *unparsed,
 	}
+	if *withFileName {
+		opts.WithFileName = true
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	results, err := grep.Process(lines, opts)
+	var index *grep.Index
+	var candidate func(string) (bool, error)
+	matcher, err := grep.Compile(opts)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
+	}
+
+	if *indexDir != "" {
+		if decompress || *follow {
+			fatal(errors.New("--index-dir cannot be combined with -z or --follow"))
+		}
+		if index, err = grep.OpenIndex(*indexDir); err != nil {
+			fatal(err)
+		}
+		if candidate, err = index.Matcher(opts); err != nil {
+			fatal(err)
+		}
 	}
 
-	for _, line := range results {
-		fmt.Println(line)
+	jobs := make(chan grep.Job)
+	walkDone := make(chan struct{})
+	go func() {
+		defer close(walkDone)
+		defer close(jobs)
+		err := grep.Walk(paths, walkOpts, func(path string, err error) error {
+			if implicitRoot {
+				path = strings.TrimPrefix(path, "."+string(filepath.Separator))
+			}
+			if err == nil && candidate != nil && path != "-" {
+				ok, indexErr := candidate(path)
+				if indexErr != nil {
+					err = indexErr
+				} else if !ok {
+					return nil
+				}
+			}
+			select {
+			case jobs <- grep.Job{Path: path, Err: err}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if index != nil {
+			var roots []string
+			if err == nil {
+				roots = paths
+			}
+			if err := index.Save(roots); err != nil {
+				log.Print(err)
+			}
+		}
+	}()
+
+	matched, failed := false, false
+	var summary grep.Summary
+	handle := func(res grep.FileResult) bool {
+		if res.Err != nil {
+			log.Print(res.Err)
+			failed = true
+		} else {
+			summary.Searches++
+		}
+		if res.Count > 0 {
+			matched = true
+			summary.SearchesWithMatch++
+			summary.MatchedLines += res.Count
+		}
+		return !(matched && *quiet)
+	}
+
+	open := func(path string) (io.ReadCloser, error) {
+		if *follow {
+			return grep.Follow(ctx, path, followInterval)
+		}
+		return os.Open(path)
+	}
+	search := func(path string, w io.Writer) (int, error) {
+		return searchFile(path, matcher, open, decompress, w)
+	}
+	if *workers <= 1 {
+		for job := range jobs {
+			res := grep.FileResult{Path: job.Path, Err: job.Err}
+			if res.Err == nil {
+				res.Count, res.Err = search(job.Path, os.Stdout)
+			}
+			if !handle(res) {
+				break
+			}
+		}
+	} else {
+		for res := range grep.SearchAll(ctx, jobs, *workers, os.Stdout, search) {
+			if !handle(res) {
+				break
+			}
+		}
+	}
+
+	cancel()
+	<-walkDone
+
+	if *jsonOut && !*quiet {
+		summary.Elapsed = time.Since(start)
+		if err := grep.WriteJSONSummary(os.Stdout, summary); err != nil {
+			fatal(err)
+		}
+	}
+
+	switch {
+	case matched && *quiet:
+		os.Exit(exitMatch)
+	case failed:
+		os.Exit(exitError)
+	case !matched:
+		os.Exit(exitNoMatch)
+	}
+}
+
+func fatal(err error) {
+	log.Print(err)
+	os.Exit(exitError)
+}
+
+func searchFile(path string, matcher *grep.Matcher, open func(string) (io.ReadCloser, error), decompress bool, w io.Writer) (int, error) {
+	var input io.Reader = os.Stdin
+	fileName := "(standard input)"
+	if path != "-" {
+		f, err := open(path)
+		if err != nil {
+			return 0, err
+		}
+		defer func(f io.ReadCloser) {
+			err := f.Close()
+			if err != nil {
+				log.Print(err)
+			}
+		}(f)
+		input = f
+		fileName = path
+	}
+
+	if decompress {
+		rc, err := grep.Decompress(input)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", fileName, err)
+		}
+		defer func(rc io.ReadCloser) {
+			_ = rc.Close()
+		}(rc)
+		input = rc
+	}
+
+	cnt, err := matcher.Run(input, w, fileName)
+	if err != nil {
+		return cnt, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return cnt, nil
+}
+
+func colorEnabled(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		info, err := os.Stdout.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb", nil
+	}
+	return false, fmt.Errorf("invalid color mode: %q", mode)
+}
+
+func readPatterns(fileName string) ([]string, error) {
+	if fileName == "-" {
+		return grep.ReadLines(os.Stdin)
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
 	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+	return grep.ReadLines(f)
 }
@@ -6,15 +6,30 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type Options struct {
 	Fields    []int
+	Bytes     []int
+	Chars     []int
+	NoSplit   bool
 	Delimiter string
 	Separated bool
 }
 
 func ProcessLine(line string, opts Options) (string, bool) {
+	return processLine(line, opts, positionSet(opts))
+}
+
+func processLine(line string, opts Options, positions map[int]bool) (string, bool) {
+	switch {
+	case len(opts.Bytes) > 0:
+		return cutBytes(line, positions, opts.NoSplit), true
+	case len(opts.Chars) > 0:
+		return cutChars(line, positions), true
+	}
+
 	if opts.Separated && !strings.Contains(line, opts.Delimiter) {
 		return "", false
 	}
@@ -34,11 +49,51 @@ func ProcessLine(line string, opts Options) (string, bool) {
 	return strings.Join(selected, opts.Delimiter), true
 }
 
+func cutBytes(line string, selected map[int]bool, noSplit bool) string {
+	var b strings.Builder
+	for i := 0; i < len(line); {
+		size := 1
+		if noSplit {
+			_, size = utf8.DecodeRuneInString(line[i:])
+		}
+		if selected[i+size] {
+			b.WriteString(line[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+func cutChars(line string, selected map[int]bool) string {
+	var b strings.Builder
+	pos := 0
+	for _, r := range line {
+		pos++
+		if selected[pos] {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func positionSet(opts Options) map[int]bool {
+	positions := opts.Bytes
+	if len(positions) == 0 {
+		positions = opts.Chars
+	}
+	set := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		set[p] = true
+	}
+	return set
+}
+
 func Run(r io.Reader, w io.Writer, opts Options) error {
+	selected := positionSet(opts)
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
-		if out, ok := ProcessLine(line, opts); ok {
+		if out, ok := processLine(line, opts, selected); ok {
 			_, err := fmt.Fprintln(w, out)
 			if err != nil {
 				return err
@@ -49,6 +49,55 @@ func TestProcessLine(t *testing.T) {
 			expected: "abc",
 			ok:       true,
 		},
+		{
+			name:     "characters are runes",
+			line:     "héllo wörld",
+			opts:     Options{Chars: []int{2, 3, 4, 8}},
+			expected: "éllö",
+			ok:       true,
+		},
+		{
+			name:     "characters in input order",
+			line:     "abcdef",
+			opts:     Options{Chars: []int{5, 1, 3, 1}},
+			expected: "ace",
+			ok:       true,
+		},
+		{
+			name:     "characters out of range",
+			line:     "ab",
+			opts:     Options{Chars: []int{3}},
+			expected: "",
+			ok:       true,
+		},
+		{
+			name:     "bytes split multibyte characters",
+			line:     "héllo",
+			opts:     Options{Bytes: []int{1, 2, 4}},
+			expected: "h\xc3l",
+			ok:       true,
+		},
+		{
+			name:     "bytes with -n drop partial characters",
+			line:     "héllo",
+			opts:     Options{Bytes: []int{1, 2}, NoSplit: true},
+			expected: "h",
+			ok:       true,
+		},
+		{
+			name:     "bytes with -n keep characters ending in range",
+			line:     "héllo",
+			opts:     Options{Bytes: []int{1, 2, 3}, NoSplit: true},
+			expected: "hé",
+			ok:       true,
+		},
+		{
+			name:     "Separated ignored for bytes",
+			line:     "abc",
+			opts:     Options{Bytes: []int{2}, Delimiter: ":", Separated: true},
+			expected: "b",
+			ok:       true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -11,25 +11,34 @@ import (
 func main() {
 	fieldSpec := flag.String("f", "", "List of fields to extract (e.g., 1,3-5)")
 	delimiter := flag.String("d", "\t", "Field delimiter (default: tab character)")
+	byteSpec := flag.String("b", "", "List of byte positions to extract (e.g., 1,3-5)")
+	charSpec := flag.String("c", "", "List of character positions to extract (e.g., 1,3-5)")
+	noSplit := flag.Bool("n", false, "With -b, do not split multibyte characters (a character is kept if its last byte is selected)")
 	separated := flag.Bool("s", false, "Suppress lines without delimiter (only process lines containing the delimiter)")
 
 	flag.Parse()
 
-	if *fieldSpec == "" {
-		log.Fatal("you must specify -f option")
+	opts := cut.Options{
+		NoSplit:   *noSplit,
+		Delimiter: *delimiter,
+		Separated: *separated,
 	}
 
-	fields, err := cut.ParseFields(*fieldSpec)
+	var err error
+	switch {
+	case countSet(*fieldSpec, *byteSpec, *charSpec) != 1:
+		log.Fatal("you must specify exactly one of -b, -c or -f options")
+	case *fieldSpec != "":
+		opts.Fields, err = cut.ParseFields(*fieldSpec)
+	case *byteSpec != "":
+		opts.Bytes, err = cut.ParseFields(*byteSpec)
+	case *charSpec != "":
+		opts.Chars, err = cut.ParseFields(*charSpec)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	opts := cut.Options{
-		Fields:    fields,
-		Delimiter: *delimiter,
-		Separated: *separated,
-	}
-
 	files := flag.Args()
 	if len(files) == 0 {
 		if err := cut.Run(os.Stdin, os.Stdout, opts); err != nil {
@@ -54,3 +63,13 @@ func main() {
 		}
 	}
 }
+
+func countSet(specs ...string) int {
+	n := 0
+	for _, s := range specs {
+		if s != "" {
+			n++
+		}
+	}
+	return n
+}